@@ -22,20 +22,25 @@ import (
 	"os"
 
 	"github.com/alexflint/go-arg"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/version"
 )
 
 type SetupCmd struct {
-	Threshold uint16 `arg:"--threshold" help:"Number of shares required to unlock drives" default:"3"`
-	Shares    uint16 `arg:"--shares"    help:"Number of shares to split into"             default:"5"`
+	Threshold uint16 `arg:"--threshold" help:"Number of shares required to unlock drives"                    default:"3"`
+	Shares    uint16 `arg:"--shares"    help:"Number of shares to split into"                                default:"5"`
+	Recovery  bool   `arg:"--recovery"  help:"Create an extra share protected by a passphrase read from stdin"`
 }
 
 type ObscureCmd struct{}
 
 type UnlockCmd struct {
-	RetryDelay    uint16 `arg:"--retry-delay,env:RETRY_DELAY"       help:"Delay between retries in seconds"          default:"60"`
+	RetryDelay    uint16 `arg:"--retry-delay,env:RETRY_DELAY"       help:"Initial delay between retries in seconds"  default:"60"`
 	ServerTimeout uint16 `arg:"--server-timeout,env:SERVER_TIMEOUT" help:"Timeout for server connections in seconds" default:"30"`
 	Test          bool   `arg:"--test"                              help:"Run in test mode"`
+	Plan          bool   `arg:"--plan"                              help:"Show the plan without fetching any share"`
+	Passphrase    bool   `arg:"--passphrase"                        help:"Use recovery share, passphrase on stdin"`
+	Deadline      uint32 `arg:"--deadline,env:UNLOCK_DEADLINE"      help:"Overall time limit in seconds (0: none)"   default:"0"`
 }
 
 type TestPathCmd struct {
@@ -43,30 +48,80 @@ type TestPathCmd struct {
 	ServerTimeout uint16 `arg:"--server-timeout,env:SERVER_TIMEOUT" help:"Timeout for server connections in seconds" default:"30"`
 }
 
+type CheckCmd struct {
+	ServerTimeout uint16 `arg:"--server-timeout,env:SERVER_TIMEOUT" help:"Timeout for server connections in seconds" default:"30"`
+	Interactive   bool   `arg:"--interactive"                       help:"Also fetch approval and USB locations"`
+}
+
+type MonitorCmd struct {
+	Interval      uint32   `arg:"--interval,env:MONITOR_INTERVAL"     help:"Seconds between checks (0: check once, for cron)" default:"0"`
+	Margin        uint16   `arg:"--margin"                            help:"Warn when fewer spare shares than this remain"    default:"1"`
+	ServerTimeout uint16   `arg:"--server-timeout,env:SERVER_TIMEOUT" help:"Timeout for server connections in seconds"        default:"30"`
+	History       string   `arg:"--history"                           help:"Path to history file"                             default:"/var/local/auto-unlock/monitor.json"`
+	Webhook       []string `arg:"--webhook"                           help:"POST alerts as JSON to this URL (repeatable)"`
+	NoNotify      bool     `arg:"--no-notify"                         help:"Do not raise Unraid notifications"`
+	Interactive   bool     `arg:"--interactive"                       help:"Also fetch approval and USB locations"`
+}
+
 type ResetCmd struct {
 	Force bool `arg:"--force" help:"Force reset without confirmation"`
 }
 
 type LicenseCmd struct{}
 
-type FetchShareCmd struct{}
+type ValidateCmd struct{}
+
+type FetchShareCmd struct {
+	Deadline int64 `arg:"--deadline" help:"[Internal] Unix time in milliseconds at which to abandon the fetch"`
+}
+
+type ServeCmd struct {
+	Listen      string   `arg:"--listen"       help:"Address to listen on"                              default:":8443"`
+	Dir         string   `arg:"--dir"          help:"Directory containing one file per share"           default:"/boot/config/plugins/auto-unlock/serve/shares"`
+	Cert        string   `arg:"--cert"         help:"Server certificate (generated if missing)"         default:"/boot/config/plugins/auto-unlock/serve/server.crt"`
+	Key         string   `arg:"--key"          help:"Server private key (generated if missing)"         default:"/boot/config/plugins/auto-unlock/serve/server.key"`
+	ClientCA    string   `arg:"--client-ca"    help:"Require client certificates issued by this CA"`
+	Tokens      string   `arg:"--tokens"       help:"File of accepted bearer tokens, one per line"`
+	TrustedKeys string   `arg:"--trusted-keys" help:"File of trusted device public keys, one per line"`
+	Allow       []string `arg:"--allow"        help:"Allowed client IP address or CIDR range (repeatable)"`
+	RateLimit   int      `arg:"--rate-limit"   help:"Maximum requests per minute per client IP"         default:"30"`
+	AccessLog   string   `arg:"--access-log"   help:"Append JSON access log lines to this file"`
+	MDNS        bool     `arg:"--mdns"         help:"Advertise the server on the LAN for mdns: locations"`
+	MDNSName    string   `arg:"--mdns-name"    help:"Instance name to advertise (default: hostname)"`
+}
+
+type DiscoverCmd struct {
+	Service string `arg:"--service" help:"mDNS service type to browse"        default:"_autounlock._tcp"`
+	Timeout uint16 `arg:"--timeout" help:"Time to wait for answers in seconds" default:"5"`
+}
 
 type CmdArgs struct {
 	Setup      *SetupCmd      `arg:"subcommand:setup"       help:"Setup auto-unlock configuration"`
 	Unlock     *UnlockCmd     `arg:"subcommand:unlock"      help:"Unlock drives using auto-unlock configuration"`
 	TestPath   *TestPathCmd   `arg:"subcommand:testpath"    help:"Test access to a given path"`
+	Validate   *ValidateCmd   `arg:"subcommand:validate"    help:"Check the configuration without contacting any location"`
+	Check      *CheckCmd      `arg:"subcommand:check"       help:"Fetch and verify the share of every location"`
+	Monitor    *MonitorCmd    `arg:"subcommand:monitor"     help:"Check the locations and alert when shares run low"`
 	Obscure    *ObscureCmd    `arg:"subcommand:obscure"     help:"Obscure a secret read from stdin"`
 	Reset      *ResetCmd      `arg:"subcommand:reset"       help:"Reset auto-unlock configuration"`
 	License    *LicenseCmd    `arg:"subcommand:license"     help:"Display license information"`
+	Serve      *ServeCmd      `arg:"subcommand:serve"       help:"Serve shares to other servers over HTTPS"`
+	Discover   *DiscoverCmd   `arg:"subcommand:discover"    help:"List share servers advertised on the LAN"`
 	FetchShare *FetchShareCmd `arg:"subcommand:fetch-share" help:"[Internal] Fetch a share from a path read on stdin"`
 
 	Config        string `arg:"--config"        help:"Path to config file"       default:"/boot/config/plugins/auto-unlock/config.txt"`
 	State         string `arg:"--state"         help:"Path to state file"        default:"/boot/config/plugins/auto-unlock/state.json"`
 	KeyFile       string `arg:"--keyfile"       help:"Path to plaintext keyfile" default:"/root/keyfile"`
 	EncryptedFile string `arg:"--encryptedfile" help:"Path to encrypted keyfile" default:"/boot/config/plugins/auto-unlock/unlock.enc"`
+	DeviceKey     string `arg:"--devicekey"     help:"Path to device key"`
+	RecoveryFile  string `arg:"--recoveryfile"  help:"Path to recovery share"    default:"/boot/config/plugins/auto-unlock/recovery.json"`
+
+	Debug  bool   `arg:"--debug"  help:"Enable debug logging"`
+	Pretty bool   `arg:"--pretty" help:"Enable pretty logging output"`
+	Output string `arg:"--output" help:"Result format: text or json (logs stay on stderr)" default:"text"`
 
-	Debug  bool `arg:"--debug"  help:"Enable debug logging"`
-	Pretty bool `arg:"--pretty" help:"Enable pretty logging output"`
+	// Command is the name of the selected subcommand.
+	Command string `arg:"-"`
 }
 
 func (CmdArgs) Version() string {
@@ -74,7 +129,8 @@ func (CmdArgs) Version() string {
 }
 
 func parseArgs() CmdArgs {
-	var args CmdArgs
+	// The device key default is shared with the fetchers that sign requests with it.
+	args := CmdArgs{DeviceKey: constants.DeviceKeyFile}
 
 	parser := arg.MustParse(&args)
 	if parser.Subcommand() == nil {
@@ -82,5 +138,11 @@ func parseArgs() CmdArgs {
 		os.Exit(1)
 	}
 
+	if args.Output != OutputText && args.Output != OutputJSON {
+		parser.Fail("--output must be text or json")
+	}
+
+	args.Command = parser.SubcommandNames()[0]
+
 	return args
 }
@@ -86,6 +86,7 @@ func TestCmdArgs_AllPathFields(t *testing.T) {
 		State:         "/custom/state.json",
 		KeyFile:       "/custom/keyfile",
 		EncryptedFile: "/custom/encrypted.enc",
+		DeviceKey:     "/custom/device.key",
 	}
 
 	if args.Config != "/custom/config.txt" {
@@ -103,6 +104,10 @@ func TestCmdArgs_AllPathFields(t *testing.T) {
 	if args.EncryptedFile != "/custom/encrypted.enc" {
 		t.Error("EncryptedFile field not set correctly")
 	}
+
+	if args.DeviceKey != "/custom/device.key" {
+		t.Error("DeviceKey field not set correctly")
+	}
 }
 
 func TestCmdArgs_DebugAndPrettyFlags(t *testing.T) {
@@ -23,6 +23,8 @@ import (
 	"os"
 
 	"github.com/dkaser/unraid-auto-unlock/autounlock/encryption"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/monitor"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/recovery"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/state"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/unraid"
@@ -38,6 +40,11 @@ type AutoUnlock struct {
 	encryption *encryption.Service
 	state      *state.Service
 	secrets    *secrets.Service
+	recovery   *recovery.Service
+	monitor    *monitor.Service
+
+	// result is the data printed by WriteResult.
+	result any
 }
 
 // NewAutoUnlock creates a new AutoUnlock instance.
@@ -49,6 +56,8 @@ func NewAutoUnlock(fs afero.Fs, args CmdArgs) (*AutoUnlock, error) {
 		unraid:     unraid.NewService(fs),
 		encryption: encryption.NewService(fs),
 		state:      state.NewService(fs),
+		recovery:   recovery.NewService(fs),
+		monitor:    monitor.NewService(fs),
 	}
 
 	// Initialize logging before constructing secrets service so that the debug
@@ -60,12 +69,14 @@ func NewAutoUnlock(fs afero.Fs, args CmdArgs) (*AutoUnlock, error) {
 		return nil, fmt.Errorf("failed to get executable path: %w", err)
 	}
 
-	autoUnlock.secrets = secrets.NewService(
+	secretsService := secrets.NewService(
 		fs,
 		execPath,
 		autoUnlock.args.Debug,
 		autoUnlock.args.Pretty,
 	)
+	secretsService.SetDeviceKey(autoUnlock.args.DeviceKey)
+	autoUnlock.secrets = secretsService
 
 	version.OutputToDebug()
 
@@ -0,0 +1,115 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
+)
+
+// CheckLocations fetches the share of every configured location through the same
+// subprocess as unlocking and reports whether the threshold can currently be met.
+// Shares are verified but never combined.
+func (a *AutoUnlock) CheckLocations() error {
+	cfg, _, err := a.secrets.ReadConfig(a.args.Config)
+	if err != nil {
+		return withCode(CodeConfigInvalid, fmt.Errorf("failed to read config file: %w", err))
+	}
+
+	appState, err := a.readState()
+	if err != nil {
+		return err
+	}
+
+	report := a.secrets.CheckLocations(
+		context.Background(),
+		cfg.Resolved(),
+		cfg.MaxConcurrency,
+		appState.SigningKey,
+		int(appState.Threshold),
+		time.Duration(a.args.Check.ServerTimeout)*time.Second,
+		a.args.Check.Interactive,
+	)
+
+	err = a.emit(report, func() error { return printCheckReport(report) })
+	if err != nil {
+		return err
+	}
+
+	if !report.Reachable {
+		return withCode(CodeInsufficientShares, errors.New("threshold is not reachable"))
+	}
+
+	return nil
+}
+
+func printCheckReport(report secrets.CheckReport) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd // Column padding
+	fmt.Fprintln(writer, "LOCATION\tMIRROR\tNAME\tFETCHER\tLATENCY\tSHARE\tRESULT")
+
+	for _, result := range report.Results {
+		status := "ok"
+
+		switch {
+		case result.Skipped:
+			status = "skipped"
+		case result.Error != "":
+			status = "error: " + result.Error
+		case result.DuplicateOf != nil:
+			status = "duplicate of location " + strconv.Itoa(*result.DuplicateOf)
+		}
+
+		fmt.Fprintf(
+			writer,
+			"%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			result.Location,
+			result.Mirror,
+			result.Name,
+			result.Fetcher,
+			time.Duration(result.LatencyMS)*time.Millisecond,
+			result.ShareID,
+			status,
+		)
+	}
+
+	err := writer.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	reachable := "reachable"
+	if !report.Reachable {
+		reachable = "NOT reachable"
+	}
+
+	fmt.Printf("\n%d of %d required shares available: threshold %s\n", report.Shares, report.Threshold, reachable)
+
+	if report.Skipped > 0 {
+		fmt.Printf("%d approval or USB locations skipped, use --interactive to check them\n", report.Skipped)
+	}
+
+	return nil
+}
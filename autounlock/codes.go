@@ -0,0 +1,121 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"errors"
+	"os"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Code identifies a class of failure. It is the code of the --output json result and
+// selects the exit status, so that scripts and the web UI can react to each one.
+type Code string
+
+const (
+	// CodeFailed is any failure without a more specific code.
+	CodeFailed             Code = "failed"
+	CodeNotUnraid          Code = "not-unraid"
+	CodeLockHeld           Code = "lock-held"
+	CodeStateMissing       Code = "state-missing"
+	CodeStateInvalid       Code = "state-invalid"
+	CodeConfigInvalid      Code = "config-invalid"
+	CodeArrayStarted       Code = "array-started"
+	CodeArrayNotStopped    Code = "array-not-stopped"
+	CodeInsufficientShares Code = "insufficient-shares"
+	CodeRecoveryFailed     Code = "recovery-failed"
+	CodeDecryptFailed      Code = "decrypt-failed"
+	CodeKeyfileInvalid     Code = "keyfile-invalid"
+	CodeStartFailed        Code = "start-failed"
+)
+
+// exitStatuses maps each code to the exit status of the process. They must never be
+// renumbered. Status 2 is left to the argument parser for usage errors.
+//
+//nolint:mnd // The statuses are the definition
+var exitStatuses = map[Code]int{
+	CodeFailed:             1,
+	CodeNotUnraid:          3,
+	CodeLockHeld:           4,
+	CodeStateMissing:       5,
+	CodeStateInvalid:       6,
+	CodeConfigInvalid:      7,
+	CodeArrayStarted:       8,
+	CodeArrayNotStopped:    9,
+	CodeInsufficientShares: 10,
+	CodeRecoveryFailed:     11,
+	CodeDecryptFailed:      12,
+	CodeKeyfileInvalid:     13,
+	CodeStartFailed:        14,
+}
+
+// CodedError is an error classified with a Code.
+type CodedError struct {
+	Code Code
+	Err  error
+}
+
+func (e *CodedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// withCode classifies err with code, unless err is nil or already classified: the
+// innermost classification is the most specific.
+func withCode(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		return err
+	}
+
+	return &CodedError{Code: code, Err: err}
+}
+
+// ErrorCode returns the code err is classified with, or CodeFailed.
+func ErrorCode(err error) Code {
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+
+	return CodeFailed
+}
+
+// ExitStatus returns the exit status for err.
+func ExitStatus(err error) int {
+	return exitStatuses[ErrorCode(err)]
+}
+
+// exit logs err and exits with its exit status. Deferred functions are not run.
+func exit(err error, msg string) {
+	log.Error().
+		Stack().
+		Err(err).
+		Str("code", string(ErrorCode(err))).
+		Msg(msg)
+	os.Exit(ExitStatus(err))
+}
@@ -0,0 +1,68 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// Testing objectives:
+// - Verify that codes are found through wrapped errors and unclassified errors fail with 1.
+// - Verify that the innermost classification is kept.
+// - Ensure that every code has a distinct exit status that is not the usage status 2.
+
+// TestErrorCode tests classification through wrapping.
+func TestErrorCode(t *testing.T) {
+	if withCode(CodeStartFailed, nil) != nil {
+		t.Error("withCode(nil) should be nil")
+	}
+
+	plain := errors.New("boom")
+	if ErrorCode(plain) != CodeFailed || ExitStatus(plain) != 1 {
+		t.Errorf("unclassified error: code=%s status=%d", ErrorCode(plain), ExitStatus(plain))
+	}
+
+	err := fmt.Errorf("failed to read: %w", withCode(CodeStateMissing, plain))
+	if ErrorCode(err) != CodeStateMissing || !errors.Is(err, plain) || err.Error() != "failed to read: boom" {
+		t.Errorf("wrapped error: code=%s err=%v", ErrorCode(err), err)
+	}
+
+	if ErrorCode(withCode(CodeFailed, err)) != CodeStateMissing {
+		t.Error("outer classification replaced the inner one")
+	}
+}
+
+// TestExitStatuses tests that exit statuses are distinct.
+func TestExitStatuses(t *testing.T) {
+	seen := make(map[int]Code)
+
+	for code, status := range exitStatuses {
+		if status == 0 || status == 2 {
+			t.Errorf("%s uses reserved exit status %d", code, status)
+		}
+
+		if other, ok := seen[status]; ok {
+			t.Errorf("%s and %s share exit status %d", code, other, status)
+		}
+
+		seen[status] = code
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// CurrentVersion is the schema version written by this release.
+const CurrentVersion = 1
+
+// Config is the structured configuration file listing the share locations.
+type Config struct {
+	Version int `toml:"version" yaml:"version"`
+	// MaxConcurrency limits the fetches in flight at once. Zero fetches without limit.
+	MaxConcurrency int        `toml:"max-concurrency,omitempty" yaml:"max-concurrency,omitempty"`
+	Defaults       Options    `toml:"defaults,omitempty"        yaml:"defaults,omitempty"`
+	Locations      []Location `toml:"locations"                 yaml:"locations"`
+}
+
+// Options are the settings that may be given per location or as defaults for all locations.
+type Options struct {
+	// Priority orders the locations: lower values are tried first, and a higher value is
+	// only tried when the lower ones cannot meet the threshold. Use it to keep metered
+	// cloud locations as a backup for cheap local ones.
+	Priority int `toml:"priority,omitempty" yaml:"priority,omitempty"`
+	// Timeout bounds each attempt at the location. Zero uses --server-timeout.
+	Timeout Duration `toml:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Backoff is the delay before the first retry, doubled after each further failure up
+	// to MaxBackoff. Zero uses --retry-delay.
+	Backoff    Duration `toml:"backoff,omitempty"     yaml:"backoff,omitempty"`
+	MaxBackoff Duration `toml:"max-backoff,omitempty" yaml:"max-backoff,omitempty"`
+	// MaxAttempts stops retrying an unreachable location. Zero retries until unlock ends.
+	MaxAttempts int `toml:"max-attempts,omitempty" yaml:"max-attempts,omitempty"`
+	// Group labels related locations, e.g. all shares held by one provider.
+	Group string `toml:"group,omitempty" yaml:"group,omitempty"`
+	// Params are handed to the fetcher in its own syntax, e.g. credentials kept out of the URL.
+	Params map[string]string `toml:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// Location is one logical share location. Fallback lists alternative locations holding
+// the same share, tried in order when URL cannot be reached.
+type Location struct {
+	Name     string   `toml:"name,omitempty"     yaml:"name,omitempty"`
+	URL      string   `toml:"url"                yaml:"url"`
+	Fallback []string `toml:"fallback,omitempty" yaml:"fallback,omitempty"`
+
+	Options `yaml:",inline"`
+}
+
+// Mirrors returns the location followed by its fallbacks, in the order they are tried.
+func (l Location) Mirrors() []string {
+	return append([]string{l.URL}, l.Fallback...)
+}
+
+// Resolved returns the locations with the defaults applied to unset options.
+// Params from the defaults are merged, with the location's own values taking precedence.
+func (c Config) Resolved() []Location {
+	locations := make([]Location, 0, len(c.Locations))
+
+	for _, location := range c.Locations {
+		if location.Priority == 0 {
+			location.Priority = c.Defaults.Priority
+		}
+
+		if location.Timeout == 0 {
+			location.Timeout = c.Defaults.Timeout
+		}
+
+		if location.Backoff == 0 {
+			location.Backoff = c.Defaults.Backoff
+		}
+
+		if location.MaxBackoff == 0 {
+			location.MaxBackoff = c.Defaults.MaxBackoff
+		}
+
+		if location.MaxAttempts == 0 {
+			location.MaxAttempts = c.Defaults.MaxAttempts
+		}
+
+		if location.Group == "" {
+			location.Group = c.Defaults.Group
+		}
+
+		if len(c.Defaults.Params) > 0 {
+			params := maps.Clone(c.Defaults.Params)
+			maps.Copy(params, location.Params)
+			location.Params = params
+		}
+
+		locations = append(locations, location)
+	}
+
+	return locations
+}
+
+// Validate checks the configuration for errors that do not depend on the fetchers.
+func (c Config) Validate() error {
+	if c.Version > CurrentVersion {
+		return fmt.Errorf("unsupported config version: %d", c.Version)
+	}
+
+	var errs []error
+
+	if c.MaxConcurrency < 0 {
+		errs = append(errs, errors.New("max-concurrency must not be negative"))
+	}
+
+	err := c.Defaults.validate()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("defaults: %w", err))
+	}
+
+	names := make(map[string]int)
+
+	for i, location := range c.Locations {
+		err := location.validate()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("location %d: %w", i+1, err))
+		}
+
+		if location.Name == "" {
+			continue
+		}
+
+		if first, found := names[location.Name]; found {
+			errs = append(errs, fmt.Errorf(
+				"location %d: name %q already used by location %d",
+				i+1,
+				location.Name,
+				first,
+			))
+		}
+
+		names[location.Name] = i + 1
+	}
+
+	return errors.Join(errs...)
+}
+
+func (l Location) validate() error {
+	for _, mirror := range l.Mirrors() {
+		switch {
+		case strings.TrimSpace(mirror) == "":
+			return errors.New("url and fallback entries must not be empty")
+		case strings.Contains(mirror, "||"):
+			return errors.New("use fallback instead of || in structured configuration")
+		}
+	}
+
+	return l.Options.validate()
+}
+
+func (o Options) validate() error {
+	switch {
+	case o.Timeout < 0, o.Backoff < 0, o.MaxBackoff < 0:
+		return errors.New("timeout and backoff must not be negative")
+	case o.MaxAttempts < 0:
+		return errors.New("max-attempts must not be negative")
+	case o.MaxBackoff > 0 && o.MaxBackoff < o.Backoff:
+		return errors.New("max-backoff must not be less than backoff")
+	}
+
+	return nil
+}
+
+// Duration is a time.Duration written as a string such as "30s" or "2m".
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", text, err)
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package config
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Testing objectives:
+// - Verify that the format is detected from the extension or the first meaningful line.
+// - Verify that YAML and TOML decode to the same configuration, including inline options.
+// - Ensure that unknown keys and invalid locations are rejected.
+// - Verify that defaults are applied to unset options and params are merged.
+// - Verify that encoded YAML parses back to the same configuration.
+
+const sampleYAML = `
+# Share locations
+version: 1
+max-concurrency: 2
+defaults:
+  timeout: 30s
+  params:
+    sig-key: /boot/device.key
+locations:
+  - name: nas
+    url: https://nas/share
+    fallback:
+      - https://mirror/share
+    timeout: 5s
+    group: lan
+    params:
+      tls-pin: sha256/PIN
+  - url: dns:share.example.com
+    priority: 10
+`
+
+const sampleTOML = `
+version = 1
+max-concurrency = 2
+
+[defaults]
+timeout = "30s"
+params = { sig-key = "/boot/device.key" }
+
+[[locations]]
+name = "nas"
+url = "https://nas/share"
+fallback = ["https://mirror/share"]
+timeout = "5s"
+group = "lan"
+params = { tls-pin = "sha256/PIN" }
+
+[[locations]]
+url = "dns:share.example.com"
+priority = 10
+`
+
+// TestDetectFormat tests format detection.
+func TestDetectFormat(t *testing.T) {
+	testCases := []struct {
+		filename string
+		data     string
+		want     Format
+	}{
+		{"config.yaml", "https://nas/share", FormatYAML},
+		{"config.toml", "", FormatTOML},
+		{"config.txt", sampleYAML, FormatYAML},
+		{"config.txt", sampleTOML, FormatTOML},
+		{"config.txt", "# comment\n\n[[locations]]\nurl = 'x'", FormatTOML},
+		{"config.txt", "# version: 1\nhttps://nas/share\n", FormatLegacy},
+		{"config.txt", "versions:share.txt\n", FormatLegacy},
+		{"config.txt", ":sftp,host=nas:/share\n", FormatLegacy},
+		{"config.txt", "", FormatLegacy},
+	}
+
+	for _, tc := range testCases {
+		got := DetectFormat(tc.filename, []byte(tc.data))
+		if got != tc.want {
+			t.Errorf("DetectFormat(%q, %q) = %s, want %s", tc.filename, tc.data, got, tc.want)
+		}
+	}
+}
+
+// TestParse_Formats tests that YAML and TOML produce the same configuration.
+func TestParse_Formats(t *testing.T) {
+	for format, data := range map[Format]string{FormatYAML: sampleYAML, FormatTOML: sampleTOML} {
+		cfg, err := Parse([]byte(data), format)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", format, err)
+		}
+
+		if len(cfg.Locations) != 2 {
+			t.Fatalf("%s: got %d locations, want 2", format, len(cfg.Locations))
+		}
+
+		nas := cfg.Locations[0]
+		if nas.Name != "nas" || nas.Group != "lan" || nas.Timeout != Duration(5*time.Second) ||
+			nas.Params["tls-pin"] != "sha256/PIN" ||
+			!slices.Equal(nas.Mirrors(), []string{"https://nas/share", "https://mirror/share"}) {
+			t.Errorf("%s: unexpected location: %+v", format, nas)
+		}
+
+		if cfg.Defaults.Timeout != Duration(30*time.Second) {
+			t.Errorf("%s: default timeout = %v", format, cfg.Defaults.Timeout)
+		}
+	}
+}
+
+// TestParse_Invalid tests rejection of unknown keys and invalid locations.
+func TestParse_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"unknown key":    "locations:\n  - url: https://nas/share\n    timout: 5s\n",
+		"bad duration":   "locations:\n  - url: https://nas/share\n    timeout: soon\n",
+		"empty url":      "locations:\n  - name: nas\n",
+		"chain in url":   "locations:\n  - url: https://a/share || https://b/share\n",
+		"empty fallback": "locations:\n  - url: https://a/share\n    fallback: ['']\n",
+		"negative":       "locations:\n  - url: https://a/share\n    timeout: -1s\n",
+		"duplicate name": "locations:\n  - {name: a, url: x}\n  - {name: a, url: y}\n",
+		"version":        "version: 2\nlocations: []\n",
+		"backoff cap":    "defaults:\n  backoff: 1m\n  max-backoff: 10s\nlocations: []\n",
+		"attempts":       "locations:\n  - url: https://a/share\n    max-attempts: -1\n",
+		"concurrency":    "max-concurrency: -1\nlocations: []\n",
+	}
+
+	for name, data := range testCases {
+		_, err := Parse([]byte(data), FormatYAML)
+		if err == nil {
+			t.Errorf("%s: expected error, got none", name)
+		}
+	}
+
+	_, err := Parse([]byte("[[locations]]\nurl = 'x'\ntimout = '5s'\n"), FormatTOML)
+	if err == nil || !strings.Contains(err.Error(), "timout") {
+		t.Errorf("expected TOML error naming the unknown key, got %v", err)
+	}
+}
+
+// TestParse_CommentsOnly tests that a YAML file without a document has no locations.
+func TestParse_CommentsOnly(t *testing.T) {
+	cfg, err := Parse([]byte("# nothing yet\n"), FormatYAML)
+	if err != nil || len(cfg.Locations) != 0 {
+		t.Errorf("cfg=%+v err=%v, want empty config", cfg, err)
+	}
+}
+
+// TestResolved tests applying defaults.
+func TestResolved(t *testing.T) {
+	cfg, err := Parse([]byte(sampleYAML), FormatYAML)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	cfg.Defaults.Group = "default"
+	cfg.Defaults.Priority = 5
+	cfg.Defaults.MaxAttempts = 5
+	cfg.Locations[0].MaxAttempts = 2
+	cfg.Defaults.Params["tls-pin"] = "sha256/DEFAULT"
+
+	resolved := cfg.Resolved()
+
+	nas, dns := resolved[0], resolved[1]
+	if nas.Timeout != Duration(5*time.Second) || nas.Group != "lan" || nas.MaxAttempts != 2 || nas.Priority != 5 ||
+		nas.Params["tls-pin"] != "sha256/PIN" || nas.Params["sig-key"] != "/boot/device.key" {
+		t.Errorf("unexpected resolved location: %+v", nas)
+	}
+
+	if dns.Timeout != Duration(30*time.Second) || dns.Group != "default" || dns.MaxAttempts != 5 || dns.Priority != 10 ||
+		dns.Params["tls-pin"] != "sha256/DEFAULT" {
+		t.Errorf("unexpected resolved location: %+v", dns)
+	}
+
+	if len(cfg.Locations[1].Params) != 0 {
+		t.Error("Resolved modified the configuration")
+	}
+}
+
+// TestEncodeYAML tests that encoded configuration parses back unchanged.
+func TestEncodeYAML(t *testing.T) {
+	cfg := Config{
+		Version: CurrentVersion,
+		Locations: []Location{
+			{URL: "https://nas/share?tls-pin=sha256/a+b"},
+			{URL: ":sftp,host=nas:/share", Fallback: []string{"dns:share.example.com"}},
+		},
+	}
+
+	data, err := EncodeYAML(cfg)
+	if err != nil {
+		t.Fatalf("EncodeYAML failed: %v", err)
+	}
+
+	if DetectFormat("config.txt", data) != FormatYAML {
+		t.Errorf("encoded config is not detected as YAML:\n%s", data)
+	}
+
+	got, err := Parse(data, FormatYAML)
+	if err != nil {
+		t.Fatalf("Parse failed: %v\n%s", err, data)
+	}
+
+	if len(got.Locations) != 2 || got.Locations[0].URL != cfg.Locations[0].URL ||
+		!slices.Equal(got.Locations[1].Mirrors(), cfg.Locations[1].Mirrors()) {
+		t.Errorf("round trip mismatch:\n%s", data)
+	}
+}
@@ -0,0 +1,157 @@
+package config
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// Format identifies the syntax of a configuration file.
+type Format int
+
+const (
+	// FormatLegacy is one location per line, with # comments and || between fallbacks.
+	FormatLegacy Format = iota
+	FormatYAML
+	FormatTOML
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatYAML:
+		return "yaml"
+	case FormatTOML:
+		return "toml"
+	default:
+		return "legacy"
+	}
+}
+
+var (
+	yamlKey  = regexp.MustCompile(`^(version|defaults|locations)\s*:(\s|$)`)
+	tomlLine = regexp.MustCompile(`^((version|defaults|locations)\s*=|\[)`)
+)
+
+// DetectFormat determines the format from the file extension, or for other names
+// (such as the default config.txt) from the first line that is not blank or a comment.
+// Legacy locations never start with a top-level schema key, so existing files keep
+// their meaning.
+func DetectFormat(filename string, data []byte) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case yamlKey.MatchString(line):
+			return FormatYAML
+		case tomlLine.MatchString(line):
+			return FormatTOML
+		default:
+			return FormatLegacy
+		}
+	}
+
+	return FormatLegacy
+}
+
+// Parse decodes and validates a structured configuration. Unknown keys are rejected so
+// that misspelt options are not silently ignored.
+func Parse(data []byte, format Format) (Config, error) {
+	var (
+		cfg Config
+		err error
+	)
+
+	switch format {
+	case FormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		err = decoder.Decode(&cfg)
+		if errors.Is(err, io.EOF) {
+			// A file holding only comments has no document.
+			err = nil
+		}
+	case FormatTOML:
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		err = decoder.Decode(&cfg)
+
+		var strictErr *toml.StrictMissingError
+		if errors.As(err, &strictErr) {
+			// The default message does not name the unknown keys.
+			err = errors.New(strictErr.String())
+		}
+	case FormatLegacy:
+		return Config{}, fmt.Errorf("%s format is not structured", format)
+	}
+
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse %s config: %w", format, err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// EncodeYAML writes the configuration as YAML.
+func EncodeYAML(cfg Config) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
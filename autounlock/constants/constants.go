@@ -25,6 +25,10 @@ const (
 	ArrayStatusTimeout = 120 * time.Second
 	ArrayTimeout       = 15 * time.Minute
 	StartRetryDelay    = 30 * time.Second
+	MaxRetryBackoff    = 10 * time.Minute
+	// FetchShareWaitDelay is how long a cancelled fetch-share subprocess may take to
+	// clean up (e.g. unmount a device, which has its own 10s timeout) before it is killed.
+	FetchShareWaitDelay = 15 * time.Second
 
 	EncryptionKeyBytes = 32
 	EncryptionFileMode = 0o600
@@ -38,4 +42,8 @@ const (
 
 	LockFile     = "/run/autounlock.lock"
 	LockFileMode = 0o600
+
+	// DeviceKeyFile is the default --devicekey, used to sign share requests that do not
+	// name a key file.
+	DeviceKeyFile = "/boot/config/plugins/auto-unlock/device.key"
 )
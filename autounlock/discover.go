@@ -0,0 +1,100 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/mdns"
+)
+
+// DiscoveredServer is a share server found by discover.
+type DiscoveredServer struct {
+	Instance string `json:"instance"`
+	Address  string `json:"address"`
+	// Location is the mdns: location pinning the key the server presented.
+	Location string `json:"location,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Discover lists the share servers advertised on the LAN together with the pin of the
+// key each presented, ready to be recorded as mdns: locations.
+func (a *AutoUnlock) Discover() error {
+	found, err := mdns.Discover(
+		context.Background(),
+		a.args.Discover.Service,
+		time.Duration(a.args.Discover.Timeout)*time.Second,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to discover share servers: %w", err)
+	}
+
+	servers := make([]DiscoveredServer, 0, len(found))
+
+	for _, peer := range found {
+		server := DiscoveredServer{
+			Instance: peer.Instance,
+			Address:  peer.Address(),
+			Location: peer.Location,
+		}
+
+		if peer.Err != nil {
+			server.Location = ""
+			server.Error = peer.Err.Error()
+		}
+
+		servers = append(servers, server)
+	}
+
+	return a.emit(servers, func() error { return printDiscovered(servers) })
+}
+
+func printDiscovered(servers []DiscoveredServer) error {
+	if len(servers) == 0 {
+		fmt.Println("No share servers found")
+
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd // Column padding
+	fmt.Fprintln(writer, "INSTANCE\tADDRESS\tLOCATION")
+
+	for _, server := range servers {
+		location := server.Location
+		if server.Error != "" {
+			location = "error: " + server.Error
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", server.Instance, server.Address, location)
+	}
+
+	err := writer.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	fmt.Println("\nVerify each pin against the tls-pin logged by that server before using it.")
+
+	return nil
+}
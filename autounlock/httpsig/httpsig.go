@@ -0,0 +1,171 @@
+package httpsig
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderSignatureInput carries the covered components and signature parameters.
+	HeaderSignatureInput = "Signature-Input"
+	// HeaderSignature carries the signature value.
+	HeaderSignature = "Signature"
+
+	// Label is the signature label used in both headers.
+	Label = "autounlock"
+	// Algorithm is the RFC 9421 algorithm identifier for Ed25519.
+	Algorithm = "ed25519"
+
+	nonceBytes = 16
+)
+
+// coveredComponents are the derived components included in every signature.
+// @path and @query are used instead of @target-uri so that signatures remain valid
+// behind TLS-terminating reverse proxies.
+var coveredComponents = []string{"@method", "@authority", "@path", "@query"}
+
+// Params are the signature parameters carried in the Signature-Input header.
+type Params struct {
+	Created time.Time
+	KeyID   string
+	Nonce   string
+}
+
+// KeyID returns the default key identifier for a public key: its unpadded base64url encoding.
+func KeyID(pub ed25519.PublicKey) string {
+	return base64.RawURLEncoding.EncodeToString(pub)
+}
+
+// Sign adds Signature-Input and Signature headers to the request in the style of
+// HTTP Message Signatures (RFC 9421). If keyID is empty, the default KeyID of the public key is used.
+func Sign(req *http.Request, key ed25519.PrivateKey, keyID string, now time.Time) error {
+	if keyID == "" {
+		pub, ok := key.Public().(ed25519.PublicKey)
+		if !ok {
+			return errors.New("invalid Ed25519 private key")
+		}
+
+		keyID = KeyID(pub)
+	}
+
+	nonce := make([]byte, nonceBytes)
+
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	params := Params{
+		Created: now,
+		KeyID:   keyID,
+		Nonce:   hex.EncodeToString(nonce),
+	}
+
+	serialized := serializeParams(params)
+
+	base, err := signatureBase(req, serialized)
+	if err != nil {
+		return err
+	}
+
+	signature := ed25519.Sign(key, []byte(base))
+
+	req.Header.Set(HeaderSignatureInput, Label+"="+serialized)
+	req.Header.Set(
+		HeaderSignature,
+		Label+"=:"+base64.StdEncoding.EncodeToString(signature)+":",
+	)
+
+	return nil
+}
+
+// serializeParams produces the inner list serialization of the covered components
+// and parameters, e.g. ("@method" "@authority");created=1;keyid="k";alg="ed25519";nonce="n".
+func serializeParams(params Params) string {
+	return fmt.Sprintf(
+		"%s;created=%d;keyid=%s;alg=%s;nonce=%s",
+		serializeComponents(),
+		params.Created.Unix(),
+		strconv.Quote(params.KeyID),
+		strconv.Quote(Algorithm),
+		strconv.Quote(params.Nonce),
+	)
+}
+
+func serializeComponents() string {
+	quoted := make([]string, len(coveredComponents))
+	for i, component := range coveredComponents {
+		quoted[i] = strconv.Quote(component)
+	}
+
+	return "(" + strings.Join(quoted, " ") + ")"
+}
+
+// signatureBase builds the RFC 9421 signature base for the covered components.
+func signatureBase(req *http.Request, serializedParams string) (string, error) {
+	var builder strings.Builder
+
+	for _, component := range coveredComponents {
+		value, err := componentValue(req, component)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&builder, "%q: %s\n", component, value)
+	}
+
+	fmt.Fprintf(&builder, "%q: %s", "@signature-params", serializedParams)
+
+	return builder.String(), nil
+}
+
+func componentValue(req *http.Request, component string) (string, error) {
+	switch component {
+	case "@method":
+		return strings.ToUpper(req.Method), nil
+	case "@authority":
+		host := req.Host
+		if host == "" && req.URL != nil {
+			host = req.URL.Host
+		}
+
+		return strings.ToLower(host), nil
+	case "@path":
+		path := req.URL.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+
+		return path, nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	default:
+		return "", fmt.Errorf("unsupported component: %s", component)
+	}
+}
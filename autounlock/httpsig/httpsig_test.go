@@ -0,0 +1,244 @@
+package httpsig
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Testing objectives:
+// - Verify that a signed request verifies with the matching public key.
+// - Ensure that unknown keys, tampered requests and stale signatures are rejected.
+// - Ensure that a replayed signature is rejected.
+// - Verify that the middleware refuses unsigned requests.
+// - Verify that EnsureKeyFile creates a key once and reuses it afterwards.
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	return pub, key
+}
+
+func signedRequest(t *testing.T, key ed25519.PrivateKey, target string, now time.Time) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+
+	err := Sign(req, key, "", now)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	return req
+}
+
+func TestVerify_ValidSignature(t *testing.T) {
+	pub, key := newKey(t)
+	verifier := NewVerifier(pub)
+
+	req := signedRequest(t, key, "https://share.local/box1?x=1", time.Now())
+
+	keyID, err := verifier.Verify(req)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	if keyID != KeyID(pub) {
+		t.Errorf("keyID = %q, want %q", keyID, KeyID(pub))
+	}
+}
+
+func TestVerify_Failures(t *testing.T) {
+	pub, key := newKey(t)
+	otherPub, _ := newKey(t)
+
+	testCases := []struct {
+		name    string
+		trusted ed25519.PublicKey
+		mutate  func(req *http.Request)
+		created time.Time
+		wantErr error
+	}{
+		{"unsigned", pub, func(req *http.Request) { req.Header.Del(HeaderSignature) }, time.Now(), ErrMissingSignature},
+		{"unknown key", otherPub, func(*http.Request) {}, time.Now(), ErrUnknownKey},
+		{"tampered path", pub, func(req *http.Request) { req.URL.Path = "/box2" }, time.Now(), ErrInvalidSignature},
+		{"tampered query", pub, func(req *http.Request) { req.URL.RawQuery = "x=2" }, time.Now(), ErrInvalidSignature},
+		{"tampered host", pub, func(req *http.Request) { req.Host = "evil.local" }, time.Now(), ErrInvalidSignature},
+		{"tampered method", pub, func(req *http.Request) { req.Method = http.MethodPost }, time.Now(), ErrInvalidSignature},
+		{"stale", pub, func(*http.Request) {}, time.Now().Add(-time.Hour), ErrExpired},
+		{"future", pub, func(*http.Request) {}, time.Now().Add(time.Hour), ErrExpired},
+		{
+			"wrong algorithm",
+			pub,
+			func(req *http.Request) {
+				input := req.Header.Get(HeaderSignatureInput)
+				req.Header.Set(HeaderSignatureInput, strings.Replace(input, `"ed25519"`, `"rsa-v1_5-sha256"`, 1))
+			},
+			time.Now(),
+			ErrInvalidSignature,
+		},
+		{
+			"malformed signature",
+			pub,
+			func(req *http.Request) { req.Header.Set(HeaderSignature, Label+"=abc") },
+			time.Now(),
+			ErrInvalidSignature,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier := NewVerifier(tc.trusted)
+			req := signedRequest(t, key, "https://share.local/box1?x=1", tc.created)
+			tc.mutate(req)
+
+			_, err := verifier.Verify(req)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify_Replay(t *testing.T) {
+	pub, key := newKey(t)
+	verifier := NewVerifier(pub)
+
+	req := signedRequest(t, key, "https://share.local/box1", time.Now())
+
+	_, err := verifier.Verify(req)
+	if err != nil {
+		t.Fatalf("first Verify failed: %v", err)
+	}
+
+	_, err = verifier.Verify(req)
+	if !errors.Is(err, ErrReplayed) {
+		t.Errorf("second Verify() error = %v, want %v", err, ErrReplayed)
+	}
+}
+
+func TestVerify_CustomKeyID(t *testing.T) {
+	pub, key := newKey(t)
+	verifier := &Verifier{Keys: map[string]ed25519.PublicKey{"tower": pub}}
+
+	req := httptest.NewRequest(http.MethodGet, "https://share.local/", nil)
+
+	err := Sign(req, key, "tower", time.Now())
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	keyID, err := verifier.Verify(req)
+	if err != nil || keyID != "tower" {
+		t.Errorf("Verify() = %q, %v; want %q, nil", keyID, err, "tower")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	pub, key := newKey(t)
+	verifier := NewVerifier(pub)
+
+	handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("share"))
+	}))
+
+	unsigned := httptest.NewRecorder()
+	handler.ServeHTTP(unsigned, httptest.NewRequest(http.MethodGet, "https://share.local/", nil))
+
+	if unsigned.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned status = %d, want %d", unsigned.Code, http.StatusUnauthorized)
+	}
+
+	signed := httptest.NewRecorder()
+	handler.ServeHTTP(signed, signedRequest(t, key, "https://share.local/", time.Now()))
+
+	if signed.Code != http.StatusOK || signed.Body.String() != "share" {
+		t.Errorf("signed response = %d %q, want 200 %q", signed.Code, signed.Body.String(), "share")
+	}
+}
+
+func TestEnsureKeyFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	path := "/boot/config/plugins/auto-unlock/device.key"
+
+	pub, created, err := EnsureKeyFile(fs, path)
+	if err != nil {
+		t.Fatalf("EnsureKeyFile failed: %v", err)
+	}
+
+	if !created {
+		t.Error("expected a new key to be created")
+	}
+
+	info, err := fs.Stat(path)
+	if err != nil {
+		t.Fatalf("device key not written: %v", err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("device key mode = %o, want 600", info.Mode().Perm())
+	}
+
+	again, created, err := EnsureKeyFile(fs, path)
+	if err != nil {
+		t.Fatalf("second EnsureKeyFile failed: %v", err)
+	}
+
+	if created || !again.Equal(pub) {
+		t.Error("expected the existing key to be reused")
+	}
+}
+
+func TestEnsureKeyFile_InvalidKey(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/device.key", []byte("not a key"), 0o600)
+
+	_, _, err := EnsureKeyFile(fs, "/device.key")
+	if err == nil {
+		t.Error("expected error for invalid device key, got none")
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _ := newKey(t)
+
+	parsed, err := ParsePublicKey(KeyID(pub) + "\n")
+	if err != nil || !parsed.Equal(pub) {
+		t.Errorf("ParsePublicKey() = %v, %v", parsed, err)
+	}
+
+	_, err = ParsePublicKey("dG9vLXNob3J0")
+	if err == nil {
+		t.Error("expected error for short public key, got none")
+	}
+}
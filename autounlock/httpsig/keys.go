@@ -0,0 +1,149 @@
+package httpsig
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+	"github.com/spf13/afero"
+)
+
+const pemTypePrivateKey = "PRIVATE KEY"
+
+type keyFileContextKey struct{}
+
+// WithKeyFile returns a context carrying the path of the device key, as set with
+// --devicekey, for fetchers that sign requests without naming a key file.
+func WithKeyFile(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, keyFileContextKey{}, path)
+}
+
+// KeyFile returns the device key path carried by ctx, or the default one.
+func KeyFile(ctx context.Context) string {
+	path, ok := ctx.Value(keyFileContextKey{}).(string)
+	if !ok || path == "" {
+		return constants.DeviceKeyFile
+	}
+
+	return path
+}
+
+// EnsureKeyFile loads the device key from path, generating and writing a new key if the
+// file does not exist. The key is kept across setups so share servers do not need to be
+// reconfigured. Returns the public key and whether a new key was created.
+func EnsureKeyFile(fs afero.Fs, path string) (ed25519.PublicKey, bool, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err == nil {
+		key, err := ParsePrivateKey(data)
+		if err != nil {
+			return nil, false, err
+		}
+
+		pub, _ := key.Public().(ed25519.PublicKey)
+
+		return pub, false, nil
+	}
+
+	exists, statErr := afero.Exists(fs, path)
+	if statErr != nil || exists {
+		return nil, false, fmt.Errorf("failed to read device key: %w", err)
+	}
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to generate device key: %w", err)
+	}
+
+	encoded, err := MarshalPrivateKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = fs.MkdirAll(filepath.Dir(path), constants.StateDirMode)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create directory for device key: %w", err)
+	}
+
+	err = afero.WriteFile(fs, path, encoded, constants.StateFileMode)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to write device key: %w", err)
+	}
+
+	return pub, true, nil
+}
+
+// MarshalPrivateKey encodes an Ed25519 private key as a PKCS#8 PEM block.
+func MarshalPrivateKey(key ed25519.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal device key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePrivateKey, Bytes: der}), nil
+}
+
+// ParsePrivateKey decodes a PKCS#8 PEM encoded Ed25519 private key.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemTypePrivateKey {
+		return nil, errors.New("device key is not a PEM encoded private key")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse device key: %w", err)
+	}
+
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("device key is not an Ed25519 key")
+	}
+
+	return key, nil
+}
+
+// ParsePublicKey decodes a public key in the format printed by setup (unpadded base64url),
+// also accepting standard base64.
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	encoded = strings.TrimSpace(encoded)
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		decoded, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key encoding: %w", err)
+		}
+	}
+
+	if len(decoded) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length: %d", len(decoded))
+	}
+
+	return ed25519.PublicKey(decoded), nil
+}
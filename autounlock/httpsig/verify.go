@@ -0,0 +1,245 @@
+package httpsig
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxAge is the default window in which a signature is accepted.
+const DefaultMaxAge = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("request is not signed")
+	ErrUnknownKey       = errors.New("signature key is not trusted")
+	ErrInvalidSignature = errors.New("signature verification failed")
+	ErrExpired          = errors.New("signature is outside the accepted time window")
+	ErrReplayed         = errors.New("signature nonce has already been used")
+)
+
+// Verifier checks signed requests against a set of trusted device public keys.
+// It is safe for concurrent use.
+type Verifier struct {
+	// Keys maps key identifiers to trusted public keys.
+	Keys map[string]ed25519.PublicKey
+	// MaxAge is the maximum allowed difference between the created parameter and now.
+	// Zero means DefaultMaxAge.
+	MaxAge time.Duration
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewVerifier creates a verifier trusting the given public keys under their default key IDs.
+func NewVerifier(keys ...ed25519.PublicKey) *Verifier {
+	verifier := &Verifier{Keys: make(map[string]ed25519.PublicKey)}
+
+	for _, key := range keys {
+		verifier.Keys[KeyID(key)] = key
+	}
+
+	return verifier
+}
+
+// Verify checks the signature on a request. On success it returns the key ID that signed it.
+// Each nonce is accepted only once within the time window.
+func (v *Verifier) Verify(req *http.Request) (string, error) {
+	input := req.Header.Get(HeaderSignatureInput)
+	signatureHeader := req.Header.Get(HeaderSignature)
+
+	if input == "" || signatureHeader == "" {
+		return "", ErrMissingSignature
+	}
+
+	serialized, found := strings.CutPrefix(input, Label+"=")
+	if !found {
+		return "", fmt.Errorf("%w: missing %s label", ErrInvalidSignature, Label)
+	}
+
+	params, err := parseParams(serialized)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	key, found := v.Keys[params.KeyID]
+	if !found || len(key) != ed25519.PublicKeySize {
+		return "", ErrUnknownKey
+	}
+
+	signature, err := parseSignature(signatureHeader)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	now := v.now()
+
+	maxAge := v.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+
+	age := now.Sub(params.Created)
+	if age > maxAge || age < -maxAge {
+		return "", ErrExpired
+	}
+
+	base, err := signatureBase(req, serialized)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	if !ed25519.Verify(key, []byte(base), signature) {
+		return "", ErrInvalidSignature
+	}
+
+	if !v.useNonce(params.KeyID+"/"+params.Nonce, now, maxAge) {
+		return "", ErrReplayed
+	}
+
+	return params.KeyID, nil
+}
+
+// Middleware returns a handler that rejects requests without a valid signature
+// with 401 Unauthorized before passing them to next.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := v.Verify(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+
+	return time.Now()
+}
+
+// useNonce records a nonce, returning false if it was already seen. Expired entries are pruned.
+func (v *Verifier) useNonce(nonce string, now time.Time, maxAge time.Duration) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.nonces == nil {
+		v.nonces = make(map[string]time.Time)
+	}
+
+	for seen, at := range v.nonces {
+		if now.Sub(at) > 2*maxAge {
+			delete(v.nonces, seen)
+		}
+	}
+
+	if _, seen := v.nonces[nonce]; seen {
+		return false
+	}
+
+	v.nonces[nonce] = now
+
+	return true
+}
+
+// parseParams parses the Signature-Input value produced by serializeParams.
+// Only the exact set of covered components used by Sign is accepted.
+func parseParams(serialized string) (Params, error) {
+	var params Params
+
+	expected := serializeComponents()
+
+	rest, found := strings.CutPrefix(serialized, expected)
+	if !found {
+		return params, errors.New("unexpected covered components")
+	}
+
+	algorithm := ""
+
+	for _, item := range strings.Split(strings.TrimPrefix(rest, ";"), ";") {
+		name, value, found := strings.Cut(item, "=")
+		if !found {
+			return params, fmt.Errorf("malformed parameter: %s", item)
+		}
+
+		switch name {
+		case "created":
+			created, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return params, fmt.Errorf("invalid created parameter: %w", err)
+			}
+
+			params.Created = time.Unix(created, 0)
+		case "keyid", "alg", "nonce":
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return params, fmt.Errorf("invalid %s parameter: %w", name, err)
+			}
+
+			switch name {
+			case "keyid":
+				params.KeyID = unquoted
+			case "alg":
+				algorithm = unquoted
+			default:
+				params.Nonce = unquoted
+			}
+		default:
+			return params, fmt.Errorf("unsupported parameter: %s", name)
+		}
+	}
+
+	if params.Created.IsZero() || params.KeyID == "" || params.Nonce == "" {
+		return params, errors.New("created, keyid and nonce parameters are required")
+	}
+
+	if algorithm != Algorithm {
+		return params, fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+
+	return params, nil
+}
+
+func parseSignature(header string) ([]byte, error) {
+	value, found := strings.CutPrefix(header, Label+"=:")
+	if !found || !strings.HasSuffix(value, ":") {
+		return nil, errors.New("malformed signature header")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(value, ":"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	return signature, nil
+}
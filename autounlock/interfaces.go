@@ -19,9 +19,11 @@ package main
 */
 
 import (
+	"context"
 	"time"
 
 	"github.com/bytemare/secret-sharing/keys"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/state"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/unraid"
@@ -31,6 +33,7 @@ import (
 // Implemented by *unraid.Service.
 type UnraidOperations interface {
 	IsUnraid() bool
+	Notify(subject string, description string, importance string) error
 	TestKeyfile(keyfile string) error
 	WaitForVarIni() error
 	GetFsState() (string, error)
@@ -66,12 +69,25 @@ type SecretsOperations interface {
 	CombineSecret(shares []*keys.KeyShare) ([]byte, error)
 	GetShare(shareStr string, signingKey []byte) (*keys.KeyShare, error)
 	ReadPathsFromFile(filename string) ([]string, error)
+	ReadConfig(filename string) (config.Config, config.Format, error)
 	GetShares(
-		paths []string,
+		ctx context.Context,
+		locations []config.Location,
+		maxConcurrency int,
 		appState state.State,
 		retryInterval uint16,
 		serverTimeout uint16,
 		test bool,
 		unraidSvc *unraid.Service,
+		seed []*keys.KeyShare,
 	) ([]*keys.KeyShare, error)
+	CheckLocations(
+		ctx context.Context,
+		locations []config.Location,
+		maxConcurrency int,
+		signingKey []byte,
+		threshold int,
+		serverTimeout time.Duration,
+		interactive bool,
+	) secrets.CheckReport
 }
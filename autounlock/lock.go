@@ -19,6 +19,7 @@ package main
 */
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"syscall"
@@ -36,7 +37,12 @@ func lockApp() (*os.File, error) {
 	if err != nil {
 		file.Close()
 
-		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+		err = fmt.Errorf("failed to acquire lock: %w", err)
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			err = withCode(CodeLockHeld, err)
+		}
+
+		return nil, err
 	}
 
 	return file, nil
@@ -19,6 +19,7 @@ package main
 */
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
@@ -41,7 +42,8 @@ func main() {
 
 	autoUnlock, err := NewAutoUnlock(fs, args)
 	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("Failed to initialize AutoUnlock")
+		writeResult(os.Stdout, args, nil, err)
+		exit(err, "Failed to initialize AutoUnlock")
 	}
 
 	// fetch-share is an internal subcommand invoked as a subprocess by the parent's
@@ -50,7 +52,39 @@ func main() {
 	if args.FetchShare != nil {
 		err = autoUnlock.FetchShareFromStdin()
 		if err != nil {
-			log.Fatal().Stack().Err(err).Msg("Failed to fetch share")
+			exit(err, "Failed to fetch share")
+		}
+
+		return
+	}
+
+	// serve is long-running and does not touch the array or keyfile, so it must not
+	// hold the lock; otherwise this server could never unlock itself while serving.
+	if args.Serve != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+
+		err = autoUnlock.Serve(ctx)
+		autoUnlock.WriteResult(err)
+
+		if err != nil {
+			exit(err, "Failed to serve shares")
+		}
+
+		return
+	}
+
+	// monitor may run indefinitely and only reads the configuration, so like serve it
+	// must not hold the lock and keep the array from being unlocked.
+	if args.Monitor != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+
+		err = autoUnlock.Monitor(ctx)
+		autoUnlock.WriteResult(err)
+
+		if err != nil {
+			exit(err, "Failed to monitor share locations")
 		}
 
 		return
@@ -73,7 +107,8 @@ func main() {
 func runLockedCommand(autoUnlock *AutoUnlock, args CmdArgs) {
 	lockFile, err := lockApp()
 	if err != nil {
-		log.Fatal().Stack().Err(err).Msg("Another instance of the application is already running")
+		writeResult(os.Stdout, args, nil, err)
+		exit(err, "Another instance of the application is already running")
 	}
 	defer lockFile.Close()
 
@@ -86,13 +121,23 @@ func runLockedCommand(autoUnlock *AutoUnlock, args CmdArgs) {
 		err = autoUnlock.Setup()
 	case args.TestPath != nil:
 		err = autoUnlock.TestPath()
+	case args.Validate != nil:
+		err = autoUnlock.ValidateConfig()
+	case args.Check != nil:
+		err = autoUnlock.CheckLocations()
+	case args.Discover != nil:
+		err = autoUnlock.Discover()
+	case args.Unlock != nil && args.Unlock.Plan:
+		err = autoUnlock.PlanUnlock()
 	case args.Unlock != nil:
 		err = autoUnlock.Unlock()
 	}
 
+	autoUnlock.WriteResult(err)
+
 	if err != nil {
 		lockFile.Close()
-		log.Fatal().Stack().Err(err).Msg("Failed to execute command") //nolint:gocritic
+		exit(err, "Failed to execute command")
 	}
 }
 
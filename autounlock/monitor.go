@@ -0,0 +1,167 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/monitor"
+	"github.com/rs/zerolog/log"
+)
+
+// webhookTimeout bounds each webhook request.
+const webhookTimeout = 30 * time.Second
+
+// Monitor checks the share locations once, or every interval until ctx is done, recording
+// each check in the history file and alerting when the availability level changes.
+func (a *AutoUnlock) Monitor(ctx context.Context) error {
+	interval := time.Duration(a.args.Monitor.Interval) * time.Second
+
+	for {
+		err := a.monitorOnce(ctx)
+		if interval == 0 {
+			return err
+		}
+
+		if err != nil {
+			log.Error().Err(err).Msg("Monitor check failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (a *AutoUnlock) monitorOnce(ctx context.Context) error {
+	// Both are read on every check so that changes apply without a restart.
+	cfg, _, err := a.secrets.ReadConfig(a.args.Config)
+	if err != nil {
+		return withCode(CodeConfigInvalid, fmt.Errorf("failed to read config file: %w", err))
+	}
+
+	appState, err := a.readState()
+	if err != nil {
+		return err
+	}
+
+	report := a.secrets.CheckLocations(
+		ctx,
+		cfg.Resolved(),
+		cfg.MaxConcurrency,
+		appState.SigningKey,
+		int(appState.Threshold),
+		time.Duration(a.args.Monitor.ServerTimeout)*time.Second,
+		a.args.Monitor.Interactive,
+	)
+
+	// Fetches cut short by stopping the monitor would read as unavailable locations.
+	if ctx.Err() != nil {
+		log.Info().Msg("Check interrupted, not recording it")
+
+		return nil
+	}
+
+	// Only verified shares count; skipped locations are listed so the alert can tell them
+	// apart, and --interactive checks them.
+	entry := monitor.Entry{
+		Time:        time.Now().UTC(),
+		Shares:      report.Shares,
+		Threshold:   report.Threshold,
+		Level:       monitor.Classify(report.Shares, report.Threshold, int(a.args.Monitor.Margin)),
+		Unavailable: report.Unavailable(),
+		Skipped:     report.SkippedLocations(),
+	}
+
+	history, err := a.monitor.Load(a.args.Monitor.History)
+	if err != nil {
+		log.Warn().Err(err).Msg("Starting a new history")
+
+		history = monitor.History{}
+	}
+
+	previous := history.Add(entry)
+
+	log.Info().
+		Int("shares", entry.Shares).
+		Int("threshold", entry.Threshold).
+		Ints("unavailable", entry.Unavailable).
+		Ints("skipped", entry.Skipped).
+		Str("level", string(entry.Level)).
+		Msg("Checked share locations")
+
+	// The result is the latest check; text output is the log above.
+	var errs []error
+
+	err = a.emit(entry, nil)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	err = a.monitor.Save(a.args.Monitor.History, history)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if entry.Level != previous {
+		errs = append(errs, a.alert(ctx, monitor.Alert{Previous: previous, Entry: entry}))
+	}
+
+	return errors.Join(errs...)
+}
+
+// alert raises an Unraid notification, unless disabled, and calls every webhook.
+func (a *AutoUnlock) alert(ctx context.Context, alert monitor.Alert) error {
+	var errs []error
+
+	log.Warn().
+		Str("previous", string(alert.Previous)).
+		Str("level", string(alert.Level)).
+		Msg(alert.Subject())
+
+	if !a.args.Monitor.NoNotify {
+		importance := map[monitor.Level]string{
+			monitor.LevelOK:       "normal",
+			monitor.LevelWarning:  "warning",
+			monitor.LevelCritical: "alert",
+		}[alert.Level]
+
+		err := a.unraid.Notify(alert.Subject(), alert.Description(), importance)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+
+	for _, target := range a.args.Monitor.Webhook {
+		err := monitor.SendWebhook(ctx, client, target, alert)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", target, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
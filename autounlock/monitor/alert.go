@@ -0,0 +1,111 @@
+package monitor
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxResponseSize bounds how much of a webhook response is read before it is discarded.
+const maxResponseSize = 64 * 1024
+
+// Alert is raised when the level changes between two checks. It is the JSON body of
+// webhook requests.
+type Alert struct {
+	Previous Level `json:"previous"`
+	Entry
+}
+
+// Subject returns a one-line summary of the alert.
+func (a Alert) Subject() string {
+	switch a.Level {
+	case LevelCritical:
+		return fmt.Sprintf("Auto unlock would fail: %d of %d required shares available", a.Shares, a.Threshold)
+	case LevelWarning:
+		return fmt.Sprintf("Auto unlock has no spare shares: %d of %d required available", a.Shares, a.Threshold)
+	default:
+		return fmt.Sprintf("Auto unlock shares recovered: %d of %d required available", a.Shares, a.Threshold)
+	}
+}
+
+// Description lists the unavailable locations, and which of them were not checked.
+func (a Alert) Description() string {
+	if len(a.Unavailable) == 0 {
+		return "All locations provided a valid share."
+	}
+
+	description := "Unavailable locations: " + joinLocations(a.Unavailable)
+
+	if len(a.Skipped) > 0 {
+		description += ". Not checked (approval or USB, use --interactive): " +
+			joinLocations(a.Skipped)
+	}
+
+	return description
+}
+
+func joinLocations(locations []int) string {
+	numbers := make([]string, len(locations))
+	for i, location := range locations {
+		numbers[i] = strconv.Itoa(location)
+	}
+
+	return strings.Join(numbers, ", ")
+}
+
+// Client is the subset of *http.Client used to send webhooks.
+type Client interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// SendWebhook posts the alert as JSON to target.
+func SendWebhook(ctx context.Context, client Client, target string, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize)) //nolint:errcheck // Drain only
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package monitor
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Testing objectives:
+// - Verify that SendWebhook posts the alert as JSON.
+// - Ensure that a non-2xx response is reported as an error.
+// - Verify that the subject and description reflect the level and unavailable locations.
+
+// TestSendWebhook tests the request body and status handling.
+func TestSendWebhook(t *testing.T) {
+	var got Alert
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&got)
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	alert := Alert{
+		Previous: LevelOK,
+		Entry:    Entry{Shares: 1, Threshold: 2, Level: LevelCritical, Unavailable: []int{2, 3}},
+	}
+
+	err := SendWebhook(t.Context(), server.Client(), server.URL+"/ok", alert)
+	if err != nil {
+		t.Fatalf("SendWebhook failed: %v", err)
+	}
+
+	if got.Previous != LevelOK || got.Level != LevelCritical || got.Shares != 1 || len(got.Unavailable) != 2 {
+		t.Errorf("unexpected webhook body: %+v", got)
+	}
+
+	err = SendWebhook(t.Context(), server.Client(), server.URL+"/fail", alert)
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected status error, got %v", err)
+	}
+}
+
+// TestAlertText tests the subject and description.
+func TestAlertText(t *testing.T) {
+	alert := Alert{Entry: Entry{Shares: 1, Threshold: 2, Level: LevelCritical, Unavailable: []int{2, 3}}}
+	if !strings.Contains(alert.Subject(), "would fail") || alert.Description() != "Unavailable locations: 2, 3" {
+		t.Errorf("unexpected alert text: %q / %q", alert.Subject(), alert.Description())
+	}
+
+	alert = Alert{Previous: LevelWarning, Entry: Entry{Shares: 3, Threshold: 2, Level: LevelOK}}
+	if !strings.Contains(alert.Subject(), "recovered") ||
+		alert.Description() != "All locations provided a valid share." {
+		t.Errorf("unexpected alert text: %q / %q", alert.Subject(), alert.Description())
+	}
+
+	alert.Entry = Entry{Shares: 1, Threshold: 2, Level: LevelCritical, Unavailable: []int{2, 3}, Skipped: []int{3}}
+	want := "Unavailable locations: 2, 3. Not checked (approval or USB, use --interactive): 3"
+
+	if alert.Description() != want {
+		t.Errorf("unexpected alert text with skipped locations: %q", alert.Description())
+	}
+}
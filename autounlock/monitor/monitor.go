@@ -0,0 +1,150 @@
+package monitor
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+	"github.com/spf13/afero"
+)
+
+// MaxEntries is the number of checks kept in the history file.
+const MaxEntries = 100
+
+// Level is the share availability at the time of a check.
+type Level string
+
+const (
+	// LevelOK means more spare shares are available than the warning margin.
+	LevelOK Level = "ok"
+	// LevelWarning means the threshold can be met, but few or no spare shares remain.
+	LevelWarning Level = "warning"
+	// LevelCritical means the threshold cannot be met: the next unlock would fail.
+	LevelCritical Level = "critical"
+)
+
+// Classify returns the level for the number of valid shares found. A warning is raised
+// when fewer than margin shares beyond the threshold are available.
+func Classify(shares int, threshold int, margin int) Level {
+	switch {
+	case shares < threshold:
+		return LevelCritical
+	case shares-threshold < margin:
+		return LevelWarning
+	default:
+		return LevelOK
+	}
+}
+
+// Entry is the result of one check.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Shares    int       `json:"shares"`
+	Threshold int       `json:"threshold"`
+	Level     Level     `json:"level"`
+	// Unavailable lists the locations that provided no valid share.
+	Unavailable []int `json:"unavailable,omitempty"`
+	// Skipped lists the approval and USB locations that were not checked, which are
+	// also unavailable.
+	Skipped []int `json:"skipped,omitempty"`
+}
+
+// History is the on-disk record of recent checks, oldest first.
+type History struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Level returns the level of the latest check, or LevelOK if there was none.
+func (h History) Level() Level {
+	if len(h.Entries) == 0 {
+		return LevelOK
+	}
+
+	return h.Entries[len(h.Entries)-1].Level
+}
+
+// Add appends the entry, dropping the oldest beyond MaxEntries, and returns the level of
+// the previous check.
+func (h *History) Add(entry Entry) Level {
+	previous := h.Level()
+
+	h.Entries = append(h.Entries, entry)
+	if len(h.Entries) > MaxEntries {
+		h.Entries = h.Entries[len(h.Entries)-MaxEntries:]
+	}
+
+	return previous
+}
+
+// Service provides history file operations.
+type Service struct {
+	fs afero.Fs
+}
+
+// NewService creates a new monitor service.
+func NewService(fs afero.Fs) *Service {
+	return &Service{fs: fs}
+}
+
+// Load reads the history file. A missing file is an empty history.
+func (s *Service) Load(path string) (History, error) {
+	data, err := afero.ReadFile(s.fs, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return History{}, nil
+	}
+
+	if err != nil {
+		return History{}, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	var history History
+
+	err = json.Unmarshal(data, &history)
+	if err != nil {
+		return History{}, fmt.Errorf("failed to parse history file: %w", err)
+	}
+
+	return history, nil
+}
+
+// Save writes the history file.
+func (s *Service) Save(path string, history History) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+
+	err = s.fs.MkdirAll(filepath.Dir(path), constants.StateDirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	err = afero.WriteFile(s.fs, path, data, constants.StateFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+
+	return nil
+}
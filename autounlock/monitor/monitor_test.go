@@ -0,0 +1,122 @@
+package monitor
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Testing objectives:
+// - Verify that Classify distinguishes critical, warning and ok levels around the margin.
+// - Verify that History.Add returns the previous level and keeps at most MaxEntries.
+// - Verify that Save and Load round-trip the history and a missing file is empty.
+// - Ensure that a corrupt history file is reported.
+
+// TestClassify tests level classification.
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		shares, threshold, margin int
+		want                      Level
+	}{
+		{1, 2, 1, LevelCritical},
+		{0, 2, 0, LevelCritical},
+		{2, 2, 1, LevelWarning},
+		{3, 2, 2, LevelWarning},
+		{3, 2, 1, LevelOK},
+		{2, 2, 0, LevelOK},
+	}
+
+	for _, tc := range testCases {
+		got := Classify(tc.shares, tc.threshold, tc.margin)
+		if got != tc.want {
+			t.Errorf("Classify(%d, %d, %d) = %s, want %s", tc.shares, tc.threshold, tc.margin, got, tc.want)
+		}
+	}
+}
+
+// TestHistoryAdd tests the previous level and trimming.
+func TestHistoryAdd(t *testing.T) {
+	var history History
+
+	if previous := history.Add(Entry{Shares: 1, Level: LevelCritical}); previous != LevelOK {
+		t.Errorf("first Add returned %s, want %s", previous, LevelOK)
+	}
+
+	if previous := history.Add(Entry{Shares: 2, Level: LevelWarning}); previous != LevelCritical {
+		t.Errorf("second Add returned %s, want %s", previous, LevelCritical)
+	}
+
+	for i := range MaxEntries {
+		history.Add(Entry{Shares: i + 3, Level: LevelOK})
+	}
+
+	if len(history.Entries) != MaxEntries {
+		t.Fatalf("history has %d entries, want %d", len(history.Entries), MaxEntries)
+	}
+
+	if history.Entries[0].Shares != 3 || history.Level() != LevelOK {
+		t.Errorf("oldest entries were not dropped: first=%+v", history.Entries[0])
+	}
+}
+
+// TestLoadSave tests the history file round trip.
+func TestLoadSave(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	svc := NewService(fs)
+	path := "/boot/config/plugins/auto-unlock/monitor.json"
+
+	history, err := svc.Load(path)
+	if err != nil || len(history.Entries) != 0 {
+		t.Fatalf("Load of missing file: history=%+v err=%v, want empty", history, err)
+	}
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	history.Add(Entry{Time: now, Shares: 2, Threshold: 3, Level: LevelCritical, Unavailable: []int{2}})
+
+	err = svc.Save(path, history)
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	got, err := svc.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if len(got.Entries) != 1 || !got.Entries[0].Time.Equal(now) || got.Level() != LevelCritical ||
+		!slices.Equal(got.Entries[0].Unavailable, []int{2}) {
+		t.Errorf("round trip mismatch: %+v", got)
+	}
+}
+
+// TestLoad_Corrupt tests that an unreadable history is reported.
+func TestLoad_Corrupt(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	svc := NewService(fs)
+	_ = afero.WriteFile(fs, "/monitor.json", []byte("{"), 0o600)
+
+	_, err := svc.Load("/monitor.json")
+	if err == nil {
+		t.Error("expected error for corrupt history file")
+	}
+}
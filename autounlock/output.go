@@ -0,0 +1,96 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Result formats selected with --output.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Result statuses.
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Result is the single document printed to stdout by a subcommand run with --output json.
+// Logs still go to stderr, so integrations never have to parse them.
+type Result struct {
+	Command string `json:"command"`
+	Status  string `json:"status"`
+	Code    Code   `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// emit records data as the result of the command with --output json, or calls printText,
+// if any, to print it for humans otherwise.
+func (a *AutoUnlock) emit(data any, printText func() error) error {
+	if a.args.Output == OutputJSON {
+		a.result = data
+
+		return nil
+	}
+
+	if printText == nil {
+		return nil
+	}
+
+	return printText()
+}
+
+// WriteResult prints the result document with --output json. err is the error the
+// command failed with, if any.
+func (a *AutoUnlock) WriteResult(err error) {
+	writeResult(os.Stdout, a.args, a.result, err)
+}
+
+// writeResult writes the result document for args to w with --output json. It is also
+// used when no AutoUnlock could be created.
+func writeResult(w io.Writer, args CmdArgs, data any, err error) {
+	if args.Output != OutputJSON {
+		return
+	}
+
+	result := Result{
+		Command: args.Command,
+		Status:  StatusOK,
+		Data:    data,
+	}
+
+	if err != nil {
+		result.Status = StatusError
+		result.Code = ErrorCode(err)
+		result.Error = err.Error()
+	}
+
+	encodeErr := json.NewEncoder(w).Encode(result)
+	if encodeErr != nil {
+		log.Error().Err(encodeErr).Msg("Failed to write result")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// Testing objectives:
+// - Verify that emit records the data with --output json and prints text otherwise.
+// - Verify that writeResult writes one document with the status, code, error and data.
+// - Ensure that nothing is written with text output.
+
+// TestEmit tests recording and printing results.
+func TestEmit(t *testing.T) {
+	printed := false
+	printText := func() error {
+		printed = true
+
+		return nil
+	}
+
+	autoUnlock := &AutoUnlock{args: CmdArgs{Output: OutputText}}
+
+	err := autoUnlock.emit("data", printText)
+	if err != nil || !printed || autoUnlock.result != nil {
+		t.Errorf("text: err=%v printed=%v result=%v", err, printed, autoUnlock.result)
+	}
+
+	printed = false
+	autoUnlock = &AutoUnlock{args: CmdArgs{Output: OutputJSON}}
+
+	err = autoUnlock.emit("data", printText)
+	if err != nil || printed || autoUnlock.result != "data" {
+		t.Errorf("json: err=%v printed=%v result=%v", err, printed, autoUnlock.result)
+	}
+}
+
+// TestWriteResult tests the result document.
+func TestWriteResult(t *testing.T) {
+	args := CmdArgs{Output: OutputJSON, Command: "check"}
+
+	var buf bytes.Buffer
+
+	writeResult(
+		&buf,
+		args,
+		map[string]int{"shares": 2},
+		withCode(CodeInsufficientShares, errors.New("threshold is not reachable")),
+	)
+
+	var result struct {
+		Command string
+		Status  string
+		Code    Code
+		Error   string
+		Data    map[string]int
+	}
+
+	err := json.Unmarshal(buf.Bytes(), &result)
+	if err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+
+	if result.Command != "check" || result.Status != StatusError || result.Code != CodeInsufficientShares ||
+		result.Error != "threshold is not reachable" || result.Data["shares"] != 2 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	buf.Reset()
+	writeResult(&buf, args, nil, nil)
+
+	if buf.String() != `{"command":"check","status":"ok"}`+"\n" {
+		t.Errorf("unexpected result: %s", buf.String())
+	}
+
+	buf.Reset()
+	writeResult(&buf, CmdArgs{Output: OutputText}, nil, nil)
+
+	if buf.Len() != 0 {
+		t.Errorf("text output wrote a result: %s", buf.String())
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
+	"github.com/rs/zerolog/log"
+)
+
+// UnlockPlan is the result of unlock --plan.
+type UnlockPlan struct {
+	Test      bool `json:"test"`
+	Threshold int  `json:"threshold"`
+	// Recovery is set when the recovery share counts toward the threshold.
+	Recovery       bool  `json:"recovery"`
+	MaxConcurrency int   `json:"maxConcurrency,omitempty"`
+	DeadlineMS     int64 `json:"deadlineMs,omitempty"`
+	// ArrayState is the current state of the array, if it could be read.
+	ArrayState string                    `json:"arrayState,omitempty"`
+	Locations  []secrets.PlannedLocation `json:"locations"`
+	// Actions are the steps taken around share collection, in order.
+	Actions []string `json:"actions"`
+}
+
+// PlanUnlock reports what unlock would do with the same flags: the order in which the
+// locations would be fetched, the fetcher, timeout and retries of each, and the array
+// actions around them. Nothing is fetched, decrypted or changed, and the recovery
+// passphrase is not asked for.
+func (a *AutoUnlock) PlanUnlock() error {
+	cfg, _, err := a.secrets.ReadConfig(a.args.Config)
+	if err != nil {
+		return withCode(CodeConfigInvalid, fmt.Errorf("failed to read config file: %w", err))
+	}
+
+	appState, err := a.readState()
+	if err != nil {
+		return err
+	}
+
+	args := a.args.Unlock
+
+	plan := UnlockPlan{
+		Test:           args.Test,
+		Threshold:      int(appState.Threshold),
+		Recovery:       args.Passphrase,
+		MaxConcurrency: cfg.MaxConcurrency,
+		DeadlineMS:     (time.Duration(args.Deadline) * time.Second).Milliseconds(),
+	}
+
+	have := 0
+	if args.Passphrase {
+		have = 1
+	}
+
+	plan.Locations = secrets.PlanLocations(
+		cfg.Resolved(),
+		have,
+		plan.Threshold,
+		cfg.MaxConcurrency,
+		time.Duration(args.RetryDelay)*time.Second,
+		time.Duration(args.ServerTimeout)*time.Second,
+		args.Test,
+	)
+
+	plan.ArrayState, err = a.unraid.GetFsState()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read the array state")
+	}
+
+	plan.Actions = a.plannedActions(plan)
+
+	return a.emit(plan, func() error { return printPlan(plan) })
+}
+
+// plannedActions lists the steps of Unlock, in order.
+func (a *AutoUnlock) plannedActions(plan UnlockPlan) []string {
+	var actions []string
+
+	if !plan.Test {
+		if plan.ArrayState == "Started" {
+			return []string{"abort: the array is already started"}
+		}
+
+		actions = append(actions, "wait for the array to be stopped")
+	}
+
+	// The recovery share counts toward the threshold.
+	need := plan.Threshold
+	if plan.Recovery {
+		actions = append(actions, "open the recovery share with the passphrase from stdin")
+		need--
+	}
+
+	if need > 0 {
+		actions = append(actions, fmt.Sprintf("collect %d shares from the locations", need))
+	}
+
+	actions = append(actions, fmt.Sprintf("decrypt %s to %s", a.args.EncryptedFile, a.args.KeyFile))
+
+	if plan.Test {
+		actions = append(actions, "test the keyfile against the array")
+	} else {
+		actions = append(actions, "start the array", "wait for the array to be started")
+	}
+
+	return append(actions, "remove "+a.args.KeyFile)
+}
+
+func printPlan(plan UnlockPlan) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd // Column padding
+	fmt.Fprintln(writer, "LOCATION\tMIRROR\tNAME\tFETCHER\tPRIORITY\tTIMEOUT\tRETRIES\tPLAN")
+
+	for _, location := range plan.Locations {
+		for _, target := range location.Targets {
+			fmt.Fprintf(
+				writer,
+				"%d\t%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				location.Location,
+				target.Mirror,
+				location.Name,
+				target.Fetcher,
+				location.Priority,
+				time.Duration(location.TimeoutMS)*time.Millisecond,
+				planRetries(location),
+				planStatus(location, target),
+			)
+		}
+	}
+
+	err := writer.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	fmt.Printf("\nThreshold: %d", plan.Threshold)
+
+	if plan.Recovery {
+		fmt.Print(", including the recovery share")
+	}
+
+	if plan.MaxConcurrency > 0 {
+		fmt.Printf(", at most %d fetches at once", plan.MaxConcurrency)
+	}
+
+	if plan.DeadlineMS > 0 {
+		fmt.Printf(", deadline %s", time.Duration(plan.DeadlineMS)*time.Millisecond)
+	}
+
+	fmt.Println()
+
+	if plan.ArrayState != "" {
+		fmt.Printf("Array: %s\n", plan.ArrayState)
+	}
+
+	fmt.Println("\nActions:")
+
+	for i, action := range plan.Actions {
+		fmt.Printf("%d. %s\n", i+1, action)
+	}
+
+	return nil
+}
+
+// planRetries formats the delays between the retries of a location.
+func planRetries(location secrets.PlannedLocation) string {
+	retries := fmt.Sprintf(
+		"%s..%s",
+		time.Duration(location.BackoffMS)*time.Millisecond,
+		time.Duration(location.MaxBackoffMS)*time.Millisecond,
+	)
+	if location.MaxAttempts > 0 {
+		retries += fmt.Sprintf(", %d attempts", location.MaxAttempts)
+	}
+
+	return retries
+}
+
+// planStatus describes when a target is fetched, or why it cannot be.
+func planStatus(location secrets.PlannedLocation, target secrets.TargetReport) string {
+	if len(target.Findings) > 0 {
+		messages := make([]string, len(target.Findings))
+		for i, finding := range target.Findings {
+			messages[i] = string(finding.Severity) + ": " + finding.Message
+		}
+
+		return strings.Join(messages, "; ")
+	}
+
+	if location.Deferred {
+		return "if needed"
+	}
+
+	if location.Queued {
+		return "when a fetch finishes"
+	}
+
+	return "start"
+}
@@ -0,0 +1,56 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"slices"
+	"testing"
+)
+
+// Testing objectives:
+// - Verify that the planned actions follow Unlock for normal and test mode.
+// - Ensure that a started array is reported as an abort.
+// - Verify that the recovery share counts toward the shares to collect.
+
+// TestPlannedActions tests the array actions around share collection.
+func TestPlannedActions(t *testing.T) {
+	autoUnlock := &AutoUnlock{args: CmdArgs{KeyFile: "/root/keyfile", EncryptedFile: "/boot/unlock.enc"}}
+
+	actions := autoUnlock.plannedActions(UnlockPlan{Threshold: 2, ArrayState: "Stopped"})
+	if actions[0] != "wait for the array to be stopped" || !slices.Contains(actions, "start the array") ||
+		actions[len(actions)-1] != "remove /root/keyfile" {
+		t.Errorf("unexpected actions: %q", actions)
+	}
+
+	actions = autoUnlock.plannedActions(UnlockPlan{Test: true, Recovery: true, Threshold: 2, ArrayState: "Started"})
+	if !slices.Contains(actions, "test the keyfile against the array") ||
+		!slices.Contains(actions, "open the recovery share with the passphrase from stdin") ||
+		slices.Contains(actions, "start the array") {
+		t.Errorf("unexpected test actions: %q", actions)
+	}
+
+	if !slices.Contains(actions, "collect 1 shares from the locations") {
+		t.Errorf("recovery share not counted: %q", actions)
+	}
+
+	actions = autoUnlock.plannedActions(UnlockPlan{Threshold: 2, ArrayState: "Started"})
+	if len(actions) != 1 || actions[0] != "abort: the array is already started" {
+		t.Errorf("unexpected actions with a started array: %q", actions)
+	}
+}
@@ -0,0 +1,202 @@
+package recovery
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	fileVersion = 1
+	kdfArgon2id = "argon2id"
+	saltBytes   = 16
+
+	// MinPassphraseLength is the shortest passphrase accepted when creating a recovery share.
+	MinPassphraseLength = 12
+
+	// Upper bounds for parameters read from the file, so that a tampered file cannot make
+	// key derivation exhaust the memory of the server.
+	maxTime    = 16
+	maxMemory  = 1024 * 1024
+	maxThreads = 16
+)
+
+// ErrWrongPassphrase is returned when the recovery share cannot be decrypted.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted recovery file")
+
+// Params are the Argon2id cost parameters. Memory is in KiB.
+type Params struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// DefaultParams follow the second recommended option of RFC 9106 (64 MiB of memory).
+var DefaultParams = Params{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// File is the on-disk format of the recovery share.
+type File struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Params     Params `json:"params"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Service provides recovery share operations.
+type Service struct {
+	fs afero.Fs
+}
+
+// NewService creates a new recovery service.
+func NewService(fs afero.Fs) *Service {
+	return &Service{fs: fs}
+}
+
+// Write encrypts the share under a key derived from the passphrase and writes it to path.
+func (s *Service) Write(path string, share string, passphrase []byte, params Params) error {
+	if len([]rune(string(passphrase))) < MinPassphraseLength {
+		return fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+	}
+
+	file := File{
+		Version: fileVersion,
+		KDF:     kdfArgon2id,
+		Params:  params,
+		Salt:    make([]byte, saltBytes),
+		Nonce:   make([]byte, constants.NonceBytes),
+	}
+
+	_, err := rand.Read(file.Salt)
+	if err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	_, err = rand.Read(file.Nonce)
+	if err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, file)
+	if err != nil {
+		return err
+	}
+
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, []byte(share), file.Salt)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal recovery file: %w", err)
+	}
+
+	err = s.fs.MkdirAll(filepath.Dir(path), constants.StateDirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for recovery file: %w", err)
+	}
+
+	err = afero.WriteFile(s.fs, path, data, constants.StateFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to write recovery file: %w", err)
+	}
+
+	return nil
+}
+
+// Read decrypts the recovery share at path with the passphrase.
+func (s *Service) Read(path string, passphrase []byte) (string, error) {
+	data, err := afero.ReadFile(s.fs, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read recovery file: %w", err)
+	}
+
+	var file File
+
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse recovery file: %w", err)
+	}
+
+	err = file.validate()
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(passphrase, file)
+	if err != nil {
+		return "", err
+	}
+
+	share, err := gcm.Open(nil, file.Nonce, file.Ciphertext, file.Salt)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+
+	return string(share), nil
+}
+
+func (f File) validate() error {
+	switch {
+	case f.Version != fileVersion:
+		return fmt.Errorf("unsupported recovery file version: %d", f.Version)
+	case f.KDF != kdfArgon2id:
+		return fmt.Errorf("unsupported key derivation function: %s", f.KDF)
+	case f.Params.Time < 1 || f.Params.Time > maxTime,
+		f.Params.Memory < 1 || f.Params.Memory > maxMemory,
+		f.Params.Threads < 1 || f.Params.Threads > maxThreads:
+		return errors.New("recovery file has invalid key derivation parameters")
+	case len(f.Salt) != saltBytes || len(f.Nonce) != constants.NonceBytes:
+		return errors.New("recovery file has invalid salt or nonce")
+	}
+
+	return nil
+}
+
+func newGCM(passphrase []byte, file File) (cipher.AEAD, error) {
+	key := argon2.IDKey(
+		passphrase,
+		file.Salt,
+		file.Params.Time,
+		file.Params.Memory,
+		file.Params.Threads,
+		constants.EncryptionKeyBytes,
+	)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return gcm, nil
+}
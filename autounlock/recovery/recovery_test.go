@@ -0,0 +1,172 @@
+package recovery
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// Testing objectives:
+// - Verify that a share written under a passphrase is read back with the same passphrase.
+// - Ensure that a wrong passphrase or a tampered file is reported as ErrWrongPassphrase.
+// - Ensure that short passphrases are refused when writing.
+// - Ensure that unsupported versions and out-of-range KDF parameters are rejected before derivation.
+
+const (
+	testPath       = "/boot/config/plugins/auto-unlock/recovery.json"
+	testPassphrase = "correct horse battery staple"
+	testShare      = "c2hhcmUtdmFsdWU="
+)
+
+// testParams keeps key derivation cheap in tests.
+var testParams = Params{Time: 1, Memory: 64, Threads: 1}
+
+func writeTestFile(t *testing.T) (*Service, afero.Fs) {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	svc := NewService(fs)
+
+	err := svc.Write(testPath, testShare, []byte(testPassphrase), testParams)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	return svc, fs
+}
+
+func rewrite(t *testing.T, fs afero.Fs, modify func(*File)) {
+	t.Helper()
+
+	data, err := afero.ReadFile(fs, testPath)
+	if err != nil {
+		t.Fatalf("failed to read recovery file: %v", err)
+	}
+
+	var file File
+
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		t.Fatalf("failed to parse recovery file: %v", err)
+	}
+
+	modify(&file)
+
+	data, err = json.Marshal(file)
+	if err != nil {
+		t.Fatalf("failed to marshal recovery file: %v", err)
+	}
+
+	err = afero.WriteFile(fs, testPath, data, 0o600)
+	if err != nil {
+		t.Fatalf("failed to write recovery file: %v", err)
+	}
+}
+
+// TestRoundTrip tests that the share is recovered with the right passphrase.
+func TestRoundTrip(t *testing.T) {
+	svc, fs := writeTestFile(t)
+
+	got, err := svc.Read(testPath, []byte(testPassphrase))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	if got != testShare {
+		t.Errorf("share = %q, want %q", got, testShare)
+	}
+
+	info, err := fs.Stat(testPath)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %o, want 600", info.Mode().Perm())
+	}
+}
+
+// TestRead_WrongPassphrase tests that a wrong passphrase is reported.
+func TestRead_WrongPassphrase(t *testing.T) {
+	svc, _ := writeTestFile(t)
+
+	_, err := svc.Read(testPath, []byte("incorrect horse battery"))
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+// TestRead_Tampered tests that modified ciphertext or salt fails authentication.
+func TestRead_Tampered(t *testing.T) {
+	for name, modify := range map[string]func(*File){
+		"ciphertext": func(f *File) { f.Ciphertext[0] ^= 1 },
+		"salt":       func(f *File) { f.Salt[0] ^= 1 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc, fs := writeTestFile(t)
+			rewrite(t, fs, modify)
+
+			_, err := svc.Read(testPath, []byte(testPassphrase))
+			if !errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("expected ErrWrongPassphrase, got %v", err)
+			}
+		})
+	}
+}
+
+// TestRead_InvalidFile tests that unsupported or unsafe files are rejected.
+func TestRead_InvalidFile(t *testing.T) {
+	for name, modify := range map[string]func(*File){
+		"version": func(f *File) { f.Version = 2 },
+		"kdf":     func(f *File) { f.KDF = "scrypt" },
+		"memory":  func(f *File) { f.Params.Memory = maxMemory + 1 },
+		"time":    func(f *File) { f.Params.Time = 0 },
+		"threads": func(f *File) { f.Params.Threads = 0 },
+		"nonce":   func(f *File) { f.Nonce = f.Nonce[:4] },
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc, fs := writeTestFile(t)
+			rewrite(t, fs, modify)
+
+			_, err := svc.Read(testPath, []byte(testPassphrase))
+			if err == nil || errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("expected validation error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestWrite_ShortPassphrase tests that short passphrases are refused.
+func TestWrite_ShortPassphrase(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	err := NewService(fs).Write(testPath, testShare, []byte("short"), testParams)
+	if err == nil {
+		t.Fatal("expected error for short passphrase, got none")
+	}
+
+	exists, _ := afero.Exists(fs, testPath)
+	if exists {
+		t.Error("recovery file written despite short passphrase")
+	}
+}
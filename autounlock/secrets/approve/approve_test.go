@@ -0,0 +1,435 @@
+package approve
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Testing objectives:
+// - Verify that approval requests are delivered in ntfy, Gotify and webhook formats.
+// - Verify that the share is returned once the request is approved via the callback.
+// - Ensure that denied requests and expired deadlines return errors.
+// - Verify that a retry after a timeout reuses the pending request without notifying again.
+// - Ensure that invalid approval URLs are rejected.
+
+// notification is a request received by the stand-in notification endpoint.
+type notification struct {
+	path    string
+	headers http.Header
+	body    string
+}
+
+// standIn is a local stand-in for both the notification service and the approval callback.
+type standIn struct {
+	*httptest.Server
+
+	share         string
+	notifications chan notification
+
+	mu        sync.Mutex
+	decisions map[string]string
+}
+
+func newStandIn(t *testing.T, share string) *standIn {
+	t.Helper()
+
+	stand := &standIn{
+		share:         share,
+		notifications: make(chan notification, 1),
+		decisions:     make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/approvals", stand.callback)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		stand.notifications <- notification{path: r.URL.Path, headers: r.Header, body: string(body)}
+	})
+
+	stand.Server = httptest.NewServer(mux)
+	t.Cleanup(stand.Close)
+
+	return stand
+}
+
+func (s *standIn) callback(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request := r.URL.Query().Get("request")
+
+	if r.Method == http.MethodPost {
+		s.decisions[request] = r.URL.Query().Get("decision")
+
+		return
+	}
+
+	switch s.decisions[request] {
+	case decisionApprove:
+		w.Write([]byte(s.share + "\n"))
+	case decisionDeny:
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// location builds an approve:// URL pointing at the stand-in.
+func (s *standIn) location(path string, params string) string {
+	host := strings.TrimPrefix(s.URL, "http://")
+	callback := url.QueryEscape(s.URL + "/approvals")
+
+	return "approve://" + host + path + "?scheme=http&poll=10ms&callback=" + callback + params
+}
+
+// decide sends a decision to the given URL as the approver's phone would.
+func decide(t *testing.T, target string) {
+	t.Helper()
+
+	resp, err := http.Post(target, "text/plain", nil)
+	if err != nil {
+		t.Errorf("failed to send decision: %v", err)
+
+		return
+	}
+
+	resp.Body.Close()
+}
+
+// waitNotification waits for the stand-in to receive a notification.
+func (s *standIn) waitNotification(t *testing.T) notification {
+	t.Helper()
+
+	select {
+	case received := <-s.notifications:
+		return received
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification received")
+	}
+
+	return notification{}
+}
+
+type fetchResult struct {
+	share string
+	err   error
+}
+
+// newFetcher returns a fetcher that keeps pending requests in a temporary directory.
+func newFetcher(t *testing.T) *Fetcher {
+	t.Helper()
+
+	return &Fetcher{PendingDir: t.TempDir()}
+}
+
+func fetchAsync(ctx context.Context, fetcher *Fetcher, location string) chan fetchResult {
+	result := make(chan fetchResult, 1)
+
+	go func() {
+		share, err := fetcher.Fetch(ctx, location)
+		result <- fetchResult{share, err}
+	}()
+
+	return result
+}
+
+func awaitResult(t *testing.T, result chan fetchResult) (string, error) {
+	t.Helper()
+
+	select {
+	case got := <-result:
+		return got.share, got.err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Fetch did not return")
+	}
+
+	return "", nil
+}
+
+var ntfyActionURL = regexp.MustCompile(`http, (\w+), ([^,]+), method=POST`)
+
+// TestFetch_NtfyApproved tests an ntfy notification with approve/deny action buttons.
+func TestFetch_NtfyApproved(t *testing.T) {
+	stand := newStandIn(t, "approved-share")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result := fetchAsync(ctx, newFetcher(t), stand.location("/unraid-unlock", "&token=tk_abc"))
+
+	received := stand.waitNotification(t)
+
+	if received.path != "/unraid-unlock" {
+		t.Errorf("notification path = %q, want /unraid-unlock", received.path)
+	}
+
+	if received.headers.Get("Authorization") != "Bearer tk_abc" {
+		t.Errorf("Authorization = %q", received.headers.Get("Authorization"))
+	}
+
+	if !strings.Contains(received.body, "requesting an unlock share") ||
+		!strings.Contains(received.body, "Expires at") {
+		t.Errorf("unexpected notification body: %q", received.body)
+	}
+
+	actions := ntfyActionURL.FindAllStringSubmatch(received.headers.Get("Actions"), -1)
+	if len(actions) != 2 || actions[0][1] != "Approve" || actions[1][1] != "Deny" {
+		t.Fatalf("unexpected actions: %q", received.headers.Get("Actions"))
+	}
+
+	decide(t, actions[0][2])
+
+	share, err := awaitResult(t, result)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if share != "approved-share" {
+		t.Errorf("share = %q, want %q", share, "approved-share")
+	}
+}
+
+// TestFetch_GotifyApproved tests a Gotify message authenticated with an application token.
+func TestFetch_GotifyApproved(t *testing.T) {
+	stand := newStandIn(t, "gotify-share")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result := fetchAsync(ctx, newFetcher(t), stand.location("", "&service=gotify&token=AppToken"))
+
+	received := stand.waitNotification(t)
+
+	if received.path != "/message" {
+		t.Errorf("notification path = %q, want /message", received.path)
+	}
+
+	if received.headers.Get("X-Gotify-Key") != "AppToken" {
+		t.Errorf("X-Gotify-Key = %q, want AppToken", received.headers.Get("X-Gotify-Key"))
+	}
+
+	var message struct {
+		Title  string `json:"title"`
+		Extras map[string]struct {
+			Click struct {
+				URL string `json:"url"`
+			} `json:"click"`
+		} `json:"extras"`
+	}
+
+	err := json.Unmarshal([]byte(received.body), &message)
+	if err != nil {
+		t.Fatalf("invalid Gotify message: %v", err)
+	}
+
+	decide(t, message.Extras["client::notification"].Click.URL)
+
+	share, err := awaitResult(t, result)
+	if err != nil || share != "gotify-share" {
+		t.Errorf("Fetch() = %q, %v; want %q", share, err, "gotify-share")
+	}
+}
+
+// TestFetch_WebhookDenied tests a webhook request that the approver denies.
+func TestFetch_WebhookDenied(t *testing.T) {
+	stand := newStandIn(t, "never-released")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result := fetchAsync(ctx, newFetcher(t), stand.location("/hook", "&service=webhook"))
+
+	received := stand.waitNotification(t)
+
+	var request approvalRequest
+
+	err := json.Unmarshal([]byte(received.body), &request)
+	if err != nil {
+		t.Fatalf("invalid webhook payload: %v", err)
+	}
+
+	if request.Request == "" || request.Expires == nil || request.Callback == "" {
+		t.Errorf("incomplete webhook payload: %+v", request)
+	}
+
+	decide(t, request.DenyURL)
+
+	_, err = awaitResult(t, result)
+	if !errors.Is(err, ErrDenied) {
+		t.Errorf("Fetch() error = %v, want %v", err, ErrDenied)
+	}
+}
+
+// TestFetch_Deadline tests that waiting stops when the context deadline passes.
+func TestFetch_Deadline(t *testing.T) {
+	stand := newStandIn(t, "late-share")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := fetchAsync(ctx, newFetcher(t), stand.location("/topic", ""))
+
+	stand.waitNotification(t)
+
+	_, err := awaitResult(t, result)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Fetch() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Fetch took %v after the deadline", elapsed)
+	}
+}
+
+// TestFetch_NotificationFailure tests that a rejected notification fails immediately.
+func TestFetch_NotificationFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	location := "approve://" + strings.TrimPrefix(server.URL, "http://") +
+		"/topic?scheme=http&callback=" + url.QueryEscape(server.URL+"/approvals")
+
+	_, err := newFetcher(t).Fetch(context.Background(), location)
+	if err == nil {
+		t.Error("expected error for rejected notification, got none")
+	}
+}
+
+// TestFetch_RetryReusesRequest tests that a retry after a timeout keeps waiting for the
+// request that was already sent instead of notifying the approver again.
+func TestFetch_RetryReusesRequest(t *testing.T) {
+	stand := newStandIn(t, "patient-share")
+	fetcher := newFetcher(t)
+	location := stand.location("/hook", "&service=webhook")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	result := fetchAsync(ctx, fetcher, location)
+	received := stand.waitNotification(t)
+
+	_, err := awaitResult(t, result)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Fetch() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	var request approvalRequest
+
+	err = json.Unmarshal([]byte(received.body), &request)
+	if err != nil {
+		t.Fatalf("invalid webhook payload: %v", err)
+	}
+
+	retryCtx, retryCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer retryCancel()
+
+	result = fetchAsync(retryCtx, fetcher, location)
+
+	decide(t, request.ApproveURL)
+
+	share, err := awaitResult(t, result)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if share != "patient-share" {
+		t.Errorf("Fetch() = %q, want %q", share, "patient-share")
+	}
+
+	select {
+	case extra := <-stand.notifications:
+		t.Errorf("retry sent another notification: %s", extra.body)
+	default:
+	}
+
+	_, err = os.Stat(fetcher.pendingFile(location))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("pending request was not removed after approval: %v", err)
+	}
+}
+
+// TestParseOptions_Invalid tests that malformed approval URLs are rejected.
+func TestParseOptions_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"missing callback": "approve://ntfy.sh/topic",
+		"missing host":     "approve:///topic?callback=https://a.local/",
+		"bad service":      "approve://ntfy.sh/topic?service=sms&callback=https://a.local/",
+		"bad scheme":       "approve://ntfy.sh/topic?scheme=ftp&callback=https://a.local/",
+		"bad callback":     "approve://ntfy.sh/topic?callback=file:///etc/passwd",
+		"bad poll":         "approve://ntfy.sh/topic?poll=soon&callback=https://a.local/",
+	}
+
+	for name, location := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseOptions(location)
+			if err == nil {
+				t.Errorf("expected error for %s, got none", location)
+			}
+		})
+	}
+}
+
+// TestParseOptions_Defaults tests the default service, scheme and poll interval.
+func TestParseOptions_Defaults(t *testing.T) {
+	opts, err := parseOptions("approve://ntfy.sh/topic?callback=https%3A%2F%2Fa.local%2Fok%3Fkey%3D1&extra=1")
+	if err != nil {
+		t.Fatalf("parseOptions failed: %v", err)
+	}
+
+	if opts.Service != serviceNtfy || opts.Poll != defaultPollInterval {
+		t.Errorf("unexpected defaults: %+v", opts)
+	}
+
+	if opts.Notify.String() != "https://ntfy.sh/topic?extra=1" {
+		t.Errorf("notify URL = %q", opts.Notify.String())
+	}
+
+	if got := opts.callbackURL("abc", decisionApprove); got != "https://a.local/ok?decision=approve&key=1&request=abc" {
+		t.Errorf("callback URL = %q", got)
+	}
+}
+
+// TestMatch tests that only approve:// locations are matched.
+func TestMatch(t *testing.T) {
+	fetcher := &Fetcher{}
+
+	if !fetcher.Match("approve://ntfy.sh/topic") {
+		t.Error("expected approve:// to match")
+	}
+
+	if fetcher.Match("https://ntfy.sh/topic") {
+		t.Error("expected https:// not to match")
+	}
+}
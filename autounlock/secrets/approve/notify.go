@@ -0,0 +1,129 @@
+package approve
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	notificationTitle = "Unraid unlock approval"
+	gotifyPriority    = 8
+)
+
+// notify delivers the approval request in the format of the configured service.
+func (o options) notify(ctx context.Context, client Client, request approvalRequest) error {
+	var (
+		target  = *o.Notify
+		body    []byte
+		headers = http.Header{}
+		err     error
+	)
+
+	switch o.Service {
+	case serviceNtfy:
+		body = []byte(request.Message)
+
+		headers.Set("Title", notificationTitle)
+		headers.Set("Tags", "key")
+		headers.Set("Priority", "high")
+		headers.Set("Actions", strings.Join([]string{
+			ntfyAction("Approve", request.ApproveURL),
+			ntfyAction("Deny", request.DenyURL),
+		}, "; "))
+	case serviceGotify:
+		target.Path = strings.TrimSuffix(target.Path, "/") + "/message"
+
+		body, err = json.Marshal(map[string]any{
+			"title":    notificationTitle,
+			"message":  request.Message + "\n\nApprove: " + request.ApproveURL + "\nDeny: " + request.DenyURL,
+			"priority": gotifyPriority,
+			"extras": map[string]any{
+				"client::notification": map[string]any{
+					"click": map[string]string{"url": request.ApproveURL},
+				},
+			},
+		})
+
+		headers.Set("Content-Type", "application/json")
+	case serviceWebhook:
+		body, err = json.Marshal(request)
+
+		headers.Set("Content-Type", "application/json")
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+
+	req.Header = headers
+
+	o.authorize(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize)) //nolint:errcheck // Drain only
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("notification endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// authorize attaches credentials for the notification endpoint.
+func (o options) authorize(req *http.Request) {
+	if o.Notify.User != nil {
+		password, _ := o.Notify.User.Password()
+		req.SetBasicAuth(o.Notify.User.Username(), password)
+		req.URL.User = nil
+	}
+
+	if o.Token == "" {
+		return
+	}
+
+	if o.Service == serviceGotify {
+		req.Header.Set("X-Gotify-Key", o.Token)
+
+		return
+	}
+
+	req.Header.Set("Authorization", "Bearer "+o.Token)
+}
+
+// ntfyAction formats an ntfy HTTP action button.
+func ntfyAction(label string, target string) string {
+	return "http, " + label + ", " + target + ", method=POST, clear=true"
+}
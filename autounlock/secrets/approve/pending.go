@@ -0,0 +1,101 @@
+package approve
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+)
+
+const (
+	// defaultPendingDir holds requests that are still waiting for approval. It lives on
+	// tmpfs so pending requests never outlive a reboot.
+	defaultPendingDir = "/run/autounlock/approve"
+
+	// pendingLifetime is how long a sent request is reused by later attempts before a
+	// new one is sent.
+	pendingLifetime = 30 * time.Minute
+)
+
+// pendingRequest is a request that was sent to the approver but not yet answered.
+type pendingRequest struct {
+	Request string    `json:"request"`
+	Sent    time.Time `json:"sent"`
+}
+
+// Expires returns when the request stops being reused.
+func (p pendingRequest) Expires() time.Time {
+	return p.Sent.Add(pendingLifetime)
+}
+
+// pendingFile returns the file that tracks the pending request for a location. Each fetch
+// runs in its own process, so the request has to be kept on disk to survive retries.
+func (f *Fetcher) pendingFile(path string) string {
+	dir := f.PendingDir
+	if dir == "" {
+		dir = defaultPendingDir
+	}
+
+	sum := sha256.Sum256([]byte(path))
+
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadPending returns the pending request stored in file, if it has not expired.
+func loadPending(file string, now time.Time) (pendingRequest, bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return pendingRequest{}, false
+	}
+
+	var request pendingRequest
+
+	err = json.Unmarshal(data, &request)
+	if err != nil || request.Request == "" || !now.Before(request.Expires()) {
+		return pendingRequest{}, false
+	}
+
+	return request, true
+}
+
+func savePending(file string, request pendingRequest) error {
+	err := os.MkdirAll(filepath.Dir(file), constants.StateDirMode)
+	if err != nil {
+		return fmt.Errorf("failed to create pending request directory: %w", err)
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending request: %w", err)
+	}
+
+	err = os.WriteFile(file, data, constants.StateFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to write pending request: %w", err)
+	}
+
+	return nil
+}
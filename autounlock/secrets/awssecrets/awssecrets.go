@@ -52,6 +52,17 @@ func (f *SecretsManagerFetcher) Priority() int {
 	return PriorityAWS
 }
 
+func (f *SecretsManagerFetcher) Name() string {
+	return "aws-secrets"
+}
+
+// Validate checks the path without loading the AWS configuration.
+func (f *SecretsManagerFetcher) Validate(path string) error {
+	_, _, _, _, err := splitAWSPath(path, "aws-secrets://") //nolint:dogsled // Only the error is needed
+
+	return err
+}
+
 func (f *SecretsManagerFetcher) Fetch(ctx context.Context, path string) (string, error) {
 	cfg, region, secretName, err := parseAWSPath(ctx, path, "aws-secrets://")
 	if err != nil {
@@ -95,6 +106,17 @@ func (f *SSMFetcher) Priority() int {
 	return PriorityAWS
 }
 
+func (f *SSMFetcher) Name() string {
+	return "aws-ssm"
+}
+
+// Validate checks the path without loading the AWS configuration.
+func (f *SSMFetcher) Validate(path string) error {
+	_, _, _, _, err := splitAWSPath(path, "aws-ssm://") //nolint:dogsled // Only the error is needed
+
+	return err
+}
+
 func (f *SSMFetcher) Fetch(ctx context.Context, path string) (string, error) {
 	cfg, region, paramName, err := parseAWSPath(ctx, path, "aws-ssm://")
 	if err != nil {
@@ -135,6 +157,26 @@ func parseAWSPath(
 	path string,
 	prefix string,
 ) (aws.Config, string, string, error) {
+	accessKey, secretKey, region, resourceName, err := splitAWSPath(path, prefix)
+	if err != nil {
+		return aws.Config{}, "", "", err
+	}
+
+	creds := credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")
+
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(region),
+		config.WithCredentialsProvider(creds),
+	)
+	if err != nil {
+		return aws.Config{}, "", "", fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return cfg, region, resourceName, nil
+}
+
+// splitAWSPath returns the access key, secret key, region and resource name of the path.
+func splitAWSPath(path string, prefix string) (string, string, string, string, error) {
 	path = strings.TrimPrefix(path, prefix)
 
 	// Regex: ^([^:]+):([^@]+)@([^/]+)/(.+)$
@@ -146,7 +188,7 @@ func parseAWSPath(
 
 	matches := re.FindStringSubmatch(path)
 	if matches == nil || len(matches) != 5 {
-		return aws.Config{}, "", "", fmt.Errorf(
+		return "", "", "", "", fmt.Errorf(
 			"invalid path format: expected %saccess_key:secret_key@region/resource",
 			prefix,
 		)
@@ -158,20 +200,10 @@ func parseAWSPath(
 	resourceName := matches[4]
 
 	if accessKey == "" || secretKey == "" || region == "" || resourceName == "" {
-		return aws.Config{}, "", "", errors.New(
+		return "", "", "", "", errors.New(
 			"all fields (access key, secret key, region, resource name) are required in path",
 		)
 	}
 
-	creds := credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")
-
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(region),
-		config.WithCredentialsProvider(creds),
-	)
-	if err != nil {
-		return aws.Config{}, "", "", fmt.Errorf("failed to load AWS config: %w", err)
-	}
-
-	return cfg, region, resourceName, nil
+	return accessKey, secretKey, region, resourceName, nil
 }
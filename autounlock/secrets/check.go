@@ -0,0 +1,279 @@
+package secrets
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/registry"
+	"github.com/rs/zerolog/log"
+)
+
+// CheckResult is the outcome of fetching one target of a location.
+type CheckResult struct {
+	Location  int    `json:"location"`
+	Mirror    int    `json:"mirror"`
+	Name      string `json:"name,omitempty"`
+	Fetcher   string `json:"fetcher,omitempty"`
+	LatencyMS int64  `json:"latencyMs"`
+	ShareID   string `json:"shareId,omitempty"`
+	// DuplicateOf is the location that already provided the same share.
+	DuplicateOf *int   `json:"duplicateOf,omitempty"`
+	Error       string `json:"error,omitempty"`
+	// Skipped is set when the target was not fetched because its fetcher is interactive.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// CheckReport is the result of CheckLocations.
+type CheckReport struct {
+	Results []CheckResult `json:"results"`
+	// Shares counts the distinct valid shares retrieved.
+	Shares int `json:"shares"`
+	// Skipped counts the locations that were not checked because every target was
+	// skipped. They do not count toward Reachable.
+	Skipped   int  `json:"skipped,omitempty"`
+	Threshold int  `json:"threshold"`
+	Reachable bool `json:"reachable"`
+}
+
+// Unavailable returns the locations that provided no valid share of their own, including
+// the skipped ones.
+func (r CheckReport) Unavailable() []int {
+	available := make(map[int]bool)
+
+	for _, result := range r.Results {
+		if result.ShareID != "" && result.DuplicateOf == nil {
+			available[result.Location] = true
+		}
+	}
+
+	var unavailable []int
+
+	for _, result := range r.Results {
+		if !available[result.Location] && !slices.Contains(unavailable, result.Location) {
+			unavailable = append(unavailable, result.Location)
+		}
+	}
+
+	return unavailable
+}
+
+// SkippedLocations returns the locations none of whose targets were fetched. They are
+// also unavailable, since no share was checked.
+func (r CheckReport) SkippedLocations() []int {
+	checked := make(map[int]bool)
+
+	for _, result := range r.Results {
+		if !result.Skipped {
+			checked[result.Location] = true
+		}
+	}
+
+	var skipped []int
+
+	for _, result := range r.Results {
+		if !checked[result.Location] && !slices.Contains(skipped, result.Location) {
+			skipped = append(skipped, result.Location)
+		}
+	}
+
+	return skipped
+}
+
+// CheckLocations fetches every target of every location, each within its location's
+// timeout or serverTimeout, and verifies the shares with signingKey. Targets are started
+// in order of priority, at most maxConcurrency at a time (0: no limit). Unlike unlocking,
+// nothing is retried, every priority is checked and fallbacks are fetched even if the
+// first target works, so that a broken one is not hidden. Targets of interactive
+// fetchers are skipped unless interactive is set.
+//
+//nolint:funlen // Concurrency limit and ordering
+func (s *Service) CheckLocations(
+	ctx context.Context,
+	locations []config.Location,
+	maxConcurrency int,
+	signingKey []byte,
+	threshold int,
+	serverTimeout time.Duration,
+	interactive bool,
+) CheckReport {
+	var results []CheckResult
+
+	for pathNum, location := range locations {
+		for mirror := range location.Mirrors() {
+			results = append(results, CheckResult{Location: pathNum, Mirror: mirror, Name: location.Name})
+		}
+	}
+
+	// Results stay in configuration order; they are started in order of priority.
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(
+			locations[results[a].Location].Priority,
+			locations[results[b].Location].Priority,
+		)
+	})
+
+	var (
+		wg    sync.WaitGroup
+		slots chan struct{}
+	)
+
+	if maxConcurrency > 0 {
+		slots = make(chan struct{}, maxConcurrency)
+	}
+
+	for _, i := range order {
+		location := locations[results[i].Location]
+
+		timeout := serverTimeout
+		if location.Timeout > 0 {
+			timeout = time.Duration(location.Timeout)
+		}
+
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results[i].Error = ctx.Err().Error()
+
+				continue
+			}
+		}
+
+		wg.Go(func() {
+			if slots != nil {
+				defer func() { <-slots }()
+			}
+
+			s.checkTarget(ctx, &results[i], location, signingKey, timeout, interactive)
+		})
+	}
+
+	wg.Wait()
+
+	return summarizeCheck(results, threshold)
+}
+
+// checkTarget fetches and verifies one target, recording the outcome in result.
+func (s *Service) checkTarget(
+	ctx context.Context,
+	result *CheckResult,
+	location config.Location,
+	signingKey []byte,
+	timeout time.Duration,
+	interactive bool,
+) {
+	path := location.Mirrors()[result.Mirror]
+
+	fetcher, err := MatchFetcher(path)
+	if err != nil {
+		result.Error = err.Error()
+
+		return
+	}
+
+	result.Fetcher = fetcher.Name()
+
+	prompter, ok := fetcher.(registry.Interactive)
+	if ok && prompter.Interactive() && !interactive {
+		result.Skipped = true
+
+		return
+	}
+
+	target, err := ApplyParams(path, location.Params)
+	if err != nil {
+		result.Error = err.Error()
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	shareStr, err := s.fetch(ctx, target)
+	result.LatencyMS = time.Since(start).Milliseconds()
+
+	if err != nil {
+		log.Debug().
+			Int("path", result.Location).
+			Int("mirror", result.Mirror).
+			Err(err).
+			Msg("Failed to fetch share")
+
+		result.Error = err.Error()
+
+		return
+	}
+
+	share, err := s.GetShare(shareStr, signingKey)
+	if err != nil {
+		result.Error = err.Error()
+
+		return
+	}
+
+	result.ShareID = strconv.FormatUint(uint64(share.Identifier()), 10)
+}
+
+// summarizeCheck counts the distinct shares and marks shares already provided by an
+// earlier location. Mirrors of one location are expected to hold the same share.
+func summarizeCheck(results []CheckResult, threshold int) CheckReport {
+	providers := make(map[string]int)
+
+	for i := range results {
+		result := &results[i]
+		if result.ShareID == "" {
+			continue
+		}
+
+		first, seen := providers[result.ShareID]
+		if !seen {
+			providers[result.ShareID] = result.Location
+
+			continue
+		}
+
+		if first != result.Location {
+			result.DuplicateOf = &first
+		}
+	}
+
+	report := CheckReport{
+		Results:   results,
+		Shares:    len(providers),
+		Threshold: threshold,
+		Reachable: len(providers) >= threshold,
+	}
+	report.Skipped = len(report.SkippedLocations())
+
+	return report
+}
@@ -0,0 +1,188 @@
+package secrets
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+)
+
+// Testing objectives:
+// - Verify that every target of every location is fetched, including working fallbacks.
+// - Verify that shares are verified and failures are reported per target.
+// - Ensure that the same share from different locations is flagged and counted once.
+// - Verify that the threshold is reported as reachable only with enough distinct shares.
+// - Verify that locations without a share of their own are reported as unavailable.
+// - Verify that targets start in order of priority within the concurrency limit.
+// - Ensure that interactive fetchers are skipped unless asked for, and reported as skipped.
+
+// TestCheckLocations tests results, duplicates and the threshold.
+func TestCheckLocations(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{
+		"/a":      encodeShare(secret, 0),
+		"/a-copy": encodeShare(secret, 0),
+		"/b":      encodeShare(secret, 1),
+		"/b-dup":  encodeShare(secret, 1),
+		"/forged": "bm90IGEgc2hhcmU=",
+	}
+
+	paths := LocationsFromPaths([]string{"/a || /a-copy", "/b", "/b-dup", "/forged", "/down"})
+
+	report := svc.CheckLocations(t.Context(), paths, 0, secret.SigningKey, 2, time.Second, false)
+
+	if len(report.Results) != 6 {
+		t.Fatalf("got %d results, want 6", len(report.Results))
+	}
+
+	if len(locations.fetched) != 6 {
+		t.Errorf("fetched %v, want every target", locations.fetched)
+	}
+
+	for _, result := range report.Results[:4] {
+		if result.Error != "" || result.ShareID == "" || result.Fetcher != "rclone" {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	}
+
+	if report.Results[1].DuplicateOf != nil {
+		t.Error("a fallback of the same location is not a duplicate")
+	}
+
+	if dup := report.Results[3].DuplicateOf; dup == nil || *dup != 1 {
+		t.Errorf("DuplicateOf = %v, want location 1", dup)
+	}
+
+	for _, result := range report.Results[4:] {
+		if result.Error == "" || result.ShareID != "" {
+			t.Errorf("expected an error: %+v", result)
+		}
+	}
+
+	if report.Shares != 2 || !report.Reachable {
+		t.Errorf("Shares = %d, Reachable = %v, want 2 and reachable", report.Shares, report.Reachable)
+	}
+
+	if unavailable := report.Unavailable(); !slices.Equal(unavailable, []int{2, 3, 4}) {
+		t.Errorf("Unavailable = %v, want [2 3 4]", unavailable)
+	}
+
+	report = svc.CheckLocations(t.Context(), paths[3:], 0, secret.SigningKey, 2, time.Second, false)
+	if report.Shares != 0 || report.Reachable {
+		t.Errorf("Shares = %d, Reachable = %v, want 0 and not reachable", report.Shares, report.Reachable)
+	}
+}
+
+// TestCheckLocations_Timeout tests that a hanging target is bounded by its timeout.
+func TestCheckLocations_Timeout(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+
+	svc.fetch = func(ctx context.Context, _ string) (string, error) {
+		<-ctx.Done()
+
+		return "", ctx.Err()
+	}
+
+	start := time.Now()
+
+	report := svc.CheckLocations(
+		t.Context(),
+		LocationsFromPaths([]string{"/hang-a", "/hang-b"}),
+		0,
+		secret.SigningKey,
+		1,
+		50*time.Millisecond,
+		false,
+	)
+
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("CheckLocations took %v, expected the timeout to apply", time.Since(start))
+	}
+
+	if report.Reachable || report.Results[0].Error == "" || report.Results[1].Error == "" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+// TestCheckLocations_Limits tests the concurrency limit, priority order and skipping.
+func TestCheckLocations_Limits(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{
+		"/cloud":               encodeShare(secret, 0),
+		"/nas":                 encodeShare(secret, 1),
+		"usb:LABEL=KEY:/share": encodeShare(secret, 2),
+	}
+
+	var (
+		mutex         sync.Mutex
+		running, peak int
+	)
+
+	svc.fetch = func(ctx context.Context, path string) (string, error) {
+		mutex.Lock()
+		running++
+		peak = max(peak, running)
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+
+		return locations.fetch(ctx, path)
+	}
+
+	paths := []config.Location{
+		{URL: "/cloud", Options: config.Options{Priority: 10}},
+		{URL: "usb:LABEL=KEY:/share"},
+		{URL: "/nas"},
+	}
+
+	report := svc.CheckLocations(t.Context(), paths, 1, secret.SigningKey, 3, time.Second, false)
+
+	if peak != 1 || !slices.Equal(locations.fetched, []string{"/nas", "/cloud"}) {
+		t.Errorf("peak = %d, fetched %v, want 1 and [/nas /cloud]", peak, locations.fetched)
+	}
+
+	if !report.Results[1].Skipped || report.Skipped != 1 || report.Shares != 2 || report.Reachable {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	if unavailable := report.Unavailable(); !slices.Equal(unavailable, []int{1}) {
+		t.Errorf("Unavailable = %v, want [1]", unavailable)
+	}
+
+	if skipped := report.SkippedLocations(); !slices.Equal(skipped, []int{1}) {
+		t.Errorf("SkippedLocations = %v, want [1]", skipped)
+	}
+
+	report = svc.CheckLocations(t.Context(), paths, 0, secret.SigningKey, 3, time.Second, true)
+	if report.Skipped != 0 || report.Shares != 3 || !report.Reachable {
+		t.Errorf("unexpected interactive report: %+v", report)
+	}
+}
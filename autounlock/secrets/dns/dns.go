@@ -48,6 +48,20 @@ func (f *Fetcher) Priority() int {
 	return PriorityDNS
 }
 
+func (f *Fetcher) Name() string {
+	return "dns"
+}
+
+// Validate checks that the location names a domain.
+func (f *Fetcher) Validate(path string) error {
+	domain := strings.TrimPrefix(path, "dns:")
+	if domain == "" || strings.ContainsAny(domain, " /") {
+		return fmt.Errorf("dns location must name a domain: dns:share.example.com, got %q", path)
+	}
+
+	return nil
+}
+
 func (f *Fetcher) Fetch(ctx context.Context, domain string) (string, error) {
 	// Use the configured resolver or create a default one
 	resolver := f.Resolver
@@ -27,49 +27,134 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
 	"github.com/bytemare/secret-sharing/keys"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/afero"
 
+	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/approve"    // Register approval fetcher
 	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/awssecrets" // Register AWS fetchers
 	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/dns"        // Register DNS fetcher
+	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/gitrepo"    // Register Git fetcher
 	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/http"       // Register HTTP fetcher
+	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/ldap"       // Register LDAP fetcher
+	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/local"      // Register local helper fetchers
+	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/mdns"       // Register mDNS fetcher
+	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/mqtt"       // Register MQTT fetcher
 	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/rclone"     // Register Rclone fetcher
+	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/redis"      // Register Redis fetcher
 	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/registry"
+	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/sqldb" // Register database fetchers
+	_ "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/usb"   // Register USB fetcher
 	"github.com/dkaser/unraid-auto-unlock/autounlock/state"
 )
 
+// MirrorSeparator separates alternative locations holding the same share on one line.
+const MirrorSeparator = "||"
+
+// ErrArrayStarted is returned when the array is started while shares are collected.
+var ErrArrayStarted = errors.New("array is no longer stopped, aborting share retrieval")
+
 type RetrievedShare struct {
 	Share   *keys.KeyShare
 	ShareID string
+	// Mirror is the index of the alternative location that served the share.
+	Mirror int
+}
+
+// SplitMirrors returns the alternative locations listed on a configuration line, in the
+// order they should be tried. A line without the separator is a single location.
+func SplitMirrors(line string) []string {
+	var mirrors []string
+
+	for mirror := range strings.SplitSeq(line, MirrorSeparator) {
+		mirror = strings.TrimSpace(mirror)
+		if mirror != "" {
+			mirrors = append(mirrors, mirror)
+		}
+	}
+
+	return mirrors
+}
+
+// LocationsFromPaths converts lines of the legacy configuration format into locations,
+// the first entry of a fallback chain becoming the URL.
+func LocationsFromPaths(paths []string) []config.Location {
+	locations := make([]config.Location, 0, len(paths))
+
+	for _, path := range paths {
+		mirrors := SplitMirrors(path)
+		if len(mirrors) == 0 {
+			continue
+		}
+
+		locations = append(locations, config.Location{URL: mirrors[0], Fallback: mirrors[1:]})
+	}
+
+	return locations
+}
+
+// ApplyParams adds per-location params from the configuration file to a location,
+// in the syntax of the fetcher that handles it.
+func ApplyParams(path string, params map[string]string) (string, error) {
+	if len(params) == 0 {
+		return path, nil
+	}
+
+	fetcher, err := MatchFetcher(path)
+	if err != nil {
+		return "", err
+	}
+
+	applier, ok := fetcher.(registry.ParamsApplier)
+	if !ok {
+		return "", fmt.Errorf("location does not accept params: %s", path)
+	}
+
+	result, err := applier.ApplyParams(path, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply params: %w", err)
+	}
+
+	return result, nil
+}
+
+// MatchFetcher returns the registered fetcher that handles the path: the first in
+// priority order that matches it.
+func MatchFetcher(path string) (registry.Fetcher, error) {
+	for _, fetcher := range registry.GetFetchers() {
+		if fetcher.Match(path) {
+			return fetcher, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no fetcher available for path: %s", path)
 }
 
 // FetchShare fetches a share from the specified path using the registry.
-// Tries each registered fetcher in priority order until one matches.
 func FetchShare(ctx context.Context, path string) (string, error) {
-	registeredFetchers := registry.GetFetchers()
-
-	// Try each registered fetcher in priority order.
-	for _, fetcher := range registeredFetchers {
-		if matched := fetcher.Match(path); matched {
-			result, err := fetcher.Fetch(ctx, path)
-			if err != nil {
-				return "", fmt.Errorf("failed to fetch resource: %w", err)
-			}
+	fetcher, err := MatchFetcher(path)
+	if err != nil {
+		return "", err
+	}
 
-			return result, nil
-		}
+	result, err := fetcher.Fetch(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch resource: %w", err)
 	}
 
-	return "", fmt.Errorf("no fetcher available for path: %s", path)
+	return result, nil
 }
 
 // fetchShare spawns the binary itself as a subprocess to perform the actual fetch,
-// isolating potentially-hanging backends from the parent process. exec.CommandContext
-// sends SIGKILL to the subprocess when ctx expires, ensuring the parent is never
-// blocked longer than the configured server timeout regardless of backend behaviour.
+// isolating potentially-hanging backends from the parent process. When ctx is done the
+// subprocess receives SIGTERM so that the fetcher can clean up, and SIGKILL if it has
+// not exited after FetchShareWaitDelay, so the parent is never blocked much longer than
+// the configured server timeout regardless of backend behaviour.
 func (s *Service) fetchShare(ctx context.Context, path string) (string, error) {
 	args := []string{"fetch-share"}
 	if s.debug {
@@ -80,10 +165,24 @@ func (s *Service) fetchShare(ctx context.Context, path string) (string, error) {
 		args = append(args, "--pretty")
 	}
 
+	if s.deviceKey != "" {
+		args = append(args, "--devicekey", s.deviceKey)
+	}
+
+	// Pass the deadline along so that fetchers which wait (e.g. for approval) can give up
+	// cleanly before the subprocess is killed.
+	if deadline, ok := ctx.Deadline(); ok {
+		args = append(args, "--deadline", strconv.FormatInt(deadline.UnixMilli(), 10))
+	}
+
 	//nolint:gosec // execPath is from os.Executable(), not user input
 	cmd := exec.CommandContext(ctx, s.execPath, args...)
 	cmd.Stdin = strings.NewReader(path + "\n")
 	cmd.Stderr = os.Stderr
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = constants.FetchShareWaitDelay
 
 	out, err := cmd.Output()
 	if err != nil {
@@ -93,6 +192,30 @@ func (s *Service) fetchShare(ctx context.Context, path string) (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
+// ReadConfig reads the configuration file, which is either structured (YAML or TOML) or
+// in the legacy format of one location per line. The format found is returned so that
+// legacy files can be migrated.
+func (s *Service) ReadConfig(filename string) (config.Config, config.Format, error) {
+	data, err := afero.ReadFile(s.fs, filename)
+	if err != nil {
+		return config.Config{}, config.FormatLegacy, fmt.Errorf("failed to open config file: %w", err)
+	}
+
+	format := config.DetectFormat(filename, data)
+	if format != config.FormatLegacy {
+		cfg, err := config.Parse(data, format)
+
+		return cfg, format, err //nolint:wrapcheck // Parse errors name the format
+	}
+
+	paths, err := s.ReadPathsFromFile(filename)
+	if err != nil {
+		return config.Config{}, format, err
+	}
+
+	return config.Config{Version: config.CurrentVersion, Locations: LocationsFromPaths(paths)}, format, nil
+}
+
 // ReadPathsFromFile reads share paths from a configuration file.
 func (s *Service) ReadPathsFromFile(filename string) ([]string, error) {
 	file, err := s.fs.Open(filename)
@@ -124,151 +247,314 @@ func (s *Service) ReadPathsFromFile(filename string) ([]string, error) {
 	return paths, nil
 }
 
+// fetchMirror fetches a single mirror of a location, giving up after timeout.
+func (s *Service) fetchMirror(
+	ctx context.Context,
+	target string,
+	timeout time.Duration,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return s.fetch(ctx, target)
+}
+
+// tryGetShare fetches the share for one location, trying its mirrors in order. Each mirror
+// gets its own timeout: the location's own, or serverTimeout if it has none, so a hanging
+// primary does not use up the time of its fallbacks. The returned bool reports whether the
+// location is settled: it is false if any mirror could not be reached, so that the
+// location is retried later.
 func (s *Service) tryGetShare(
-	path string,
+	ctx context.Context,
+	location config.Location,
 	pathNum int,
 	signingKey []byte,
 	serverTimeout time.Duration,
 ) (RetrievedShare, bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), serverTimeout)
-	defer cancel()
+	if location.Timeout > 0 {
+		serverTimeout = time.Duration(location.Timeout)
+	}
 
-	shareStr, err := s.fetchShare(ctx, path)
-	if err != nil {
-		log.Debug().Int("path", pathNum).Stack().Err(err).Msg("Failed to fetch share")
+	var (
+		errs        []error
+		unreachable bool
+	)
 
-		return RetrievedShare{}, false, err
-	}
+	for mirror, target := range location.Mirrors() {
+		target, err := ApplyParams(target, location.Params)
+		if err != nil {
+			log.Debug().
+				Int("path", pathNum).
+				Int("mirror", mirror).
+				Err(err).
+				Msg("Invalid location")
 
-	share, err := s.GetShare(shareStr, signingKey)
-	if err != nil {
-		log.Debug().Int("path", pathNum).Stack().Err(err).Msg("Failed to get share")
+			errs = append(errs, err)
+
+			continue
+		}
+
+		shareStr, err := s.fetchMirror(ctx, target, serverTimeout)
+		if err != nil {
+			log.Debug().
+				Int("path", pathNum).
+				Int("mirror", mirror).
+				Stack().
+				Err(err).
+				Msg("Failed to fetch share")
+
+			errs = append(errs, err)
+			unreachable = true
+
+			if ctx.Err() != nil {
+				break
+			}
+
+			continue
+		}
+
+		share, err := s.GetShare(shareStr, signingKey)
+		if err != nil {
+			log.Debug().
+				Int("path", pathNum).
+				Int("mirror", mirror).
+				Stack().
+				Err(err).
+				Msg("Failed to get share")
+
+			errs = append(errs, err)
+
+			continue
+		}
 
-		return RetrievedShare{}, true, err
+		// Use share identifier to detect duplicates
+		shareID := strconv.FormatUint(uint64(share.Identifier()), 10)
+
+		log.Info().Int("path", pathNum).Int("mirror", mirror).Msg("Successfully retrieved share")
+
+		return RetrievedShare{
+			Share:   share,
+			ShareID: shareID,
+			Mirror:  mirror,
+		}, true, nil
 	}
 
-	// Use share identifier to detect duplicates
-	shareID := strconv.FormatUint(uint64(share.Identifier()), 10)
+	if len(errs) == 0 {
+		return RetrievedShare{}, true, errors.New("no locations configured")
+	}
 
-	log.Info().Int("path", pathNum).Msg("Successfully retrieved share")
+	return RetrievedShare{}, !unreachable, errors.Join(errs...)
+}
 
-	return RetrievedShare{
-		Share:   share,
-		ShareID: shareID,
-	}, true, nil
+// fetchResult is the outcome of one attempt at a location.
+type fetchResult struct {
+	pathNum int
+	share   RetrievedShare
+	settled bool
+	err     error
 }
 
-//nolint:cyclop,funlen // Complexity and length inherent to share collection with retry logic
+// collectShares fetches the locations concurrently in order of priority, up to
+// maxConcurrency at a time, retrying unreachable ones as their backoff allows. It returns
+// as soon as the threshold is met, cancelling the fetches still in flight so that a
+// hanging location does not delay unlocking.
+//
+//nolint:cyclop,funlen,gocognit // Complexity and length inherent to share collection with retry logic
 func (s *Service) collectShares(
-	paths []string,
+	ctx context.Context,
+	locations []config.Location,
+	maxConcurrency int,
 	appState state.State,
 	retryDuration time.Duration,
 	serverTimeout time.Duration,
 	test bool,
 	unraidSvc unraidVerifier,
+	seed []*keys.KeyShare,
 ) ([]*keys.KeyShare, error) {
 	var (
 		shares     []*keys.KeyShare
-		mutex      sync.Mutex
-		triedPaths = make(map[string]bool)
+		schedule   = make([]retrySchedule, len(locations))
 		seenShares = make(map[string]bool)
+		order      = preferenceOrder(locations)
+		inFlight   int
 	)
 
+	// Shares obtained outside of the configured locations (e.g. the recovery share)
+	// count toward the threshold, and copies of them found at a location are duplicates.
+	for _, share := range seed {
+		shares = append(shares, share)
+		seenShares[strconv.FormatUint(uint64(share.Identifier()), 10)] = true
+	}
+
+	if len(shares) >= int(appState.Threshold) && !test {
+		return shares, nil
+	}
+
+	// Cancelling on return kills the fetch-share subprocesses that are still running.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Each location has at most one fetch in flight, so sends never block even after
+	// this function has returned.
+	results := make(chan fetchResult, len(locations))
+
 	for {
 		if shouldAbort(unraidSvc, test) {
-			return nil, errors.New("array is no longer stopped, aborting share retrieval")
+			return nil, ErrArrayStarted
 		}
 
-		var waitGroup sync.WaitGroup
+		now := time.Now()
+		due := dueLocations(
+			locations,
+			schedule,
+			order,
+			len(shares),
+			int(appState.Threshold),
+			maxConcurrency,
+			test,
+			now,
+		)
+
+		for _, pathNum := range due {
+			location := locations[pathNum]
+
+			schedule[pathNum].inFlight = true
+			inFlight++
+
+			go func() {
+				retrievedShare, settled, err := s.tryGetShare(
+					ctx,
+					location,
+					pathNum,
+					appState.SigningKey,
+					serverTimeout,
+				)
+				results <- fetchResult{pathNum: pathNum, share: retrievedShare, settled: settled, err: err}
+			}()
+		}
 
-		for pathNum, path := range paths {
-			// Skip paths we've already tried
-			mutex.Lock()
+		next, pending := nextRetry(schedule, now)
+		if inFlight == 0 && !pending {
+			break
+		}
 
-			alreadyTried := triedPaths[path]
+		var retry <-chan time.Time
 
-			mutex.Unlock()
+		if pending {
+			wait := time.Until(next)
 
-			if alreadyTried {
-				continue
+			if inFlight == 0 {
+				log.Warn().
+					Int("have", len(shares)).
+					Int("need", int(appState.Threshold)).
+					Dur("wait", wait).
+					Msg("Not enough shares retrieved. Waiting before retrying.")
 			}
 
-			waitGroup.Go(func() {
-				retrievedShare, fetchSucceeded, err := s.tryGetShare(
-					path,
-					pathNum,
-					appState.SigningKey,
-					serverTimeout,
-				)
+			retry = time.After(wait)
+		}
 
-				mutex.Lock()
-				defer mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf(
+				"unlock deadline reached with %d of %d shares: %w",
+				len(shares),
+				appState.Threshold,
+				ctx.Err(),
+			)
+		case <-retry:
+			continue
+		case result := <-results:
+			inFlight--
 
-				// Only mark as tried if fetch succeeded (don't retry corrupt shares)
-				if fetchSucceeded {
-					triedPaths[path] = true
-				}
+			recordResult(result, locations, schedule, retryDuration, test)
 
-				if err != nil {
-					return
-				}
+			if result.err != nil {
+				continue
+			}
 
-				// Check for duplicate shares
-				if seenShares[retrievedShare.ShareID] {
-					log.Debug().Int("path", pathNum).Msg("Duplicate share, ignoring")
+			// Check for duplicate shares
+			if seenShares[result.share.ShareID] {
+				log.Debug().Int("path", result.pathNum).Msg("Duplicate share, ignoring")
 
-					return
+				continue
+			}
+
+			shares = append(shares, result.share.Share)
+			seenShares[result.share.ShareID] = true
+
+			if len(shares) >= int(appState.Threshold) && !test {
+				if inFlight > 0 {
+					log.Debug().Int("cancelled", inFlight).Msg("Threshold reached, cancelling remaining fetches")
 				}
 
-				shares = append(shares, retrievedShare.Share)
-				seenShares[retrievedShare.ShareID] = true
-			})
+				return shares, nil
+			}
 		}
+	}
 
-		waitGroup.Wait()
+	return shares, nil
+}
 
-		if len(shares) >= int(appState.Threshold) && !test {
-			return shares, nil
-		}
+// recordResult updates the schedule of the location after an attempt. Only unreachable
+// locations are retried (not corrupt shares), and in test mode each is tried once.
+func recordResult(
+	result fetchResult,
+	locations []config.Location,
+	schedule []retrySchedule,
+	retryDuration time.Duration,
+	test bool,
+) {
+	location := locations[result.pathNum]
+	entry := &schedule[result.pathNum]
 
-		// Check if all paths have been tried
-		if len(triedPaths) >= len(paths) || test {
-			break
-		}
+	entry.inFlight = false
+	entry.attempts++
+
+	switch {
+	case result.settled, test:
+		entry.done = true
+	case location.MaxAttempts > 0 && entry.attempts >= location.MaxAttempts:
+		entry.done = true
 
-		// Wait before retrying remaining paths
 		log.Warn().
-			Int("have", len(shares)).
-			Int("need", int(appState.Threshold)).
-			Dur("wait", retryDuration).
-			Msg("Not enough shares retrieved. Waiting before retrying.")
-		time.Sleep(retryDuration)
+			Int("path", result.pathNum).
+			Int("attempts", entry.attempts).
+			Msg("Giving up on location after maximum attempts")
+	default:
+		entry.next = time.Now().Add(backoff(location, retryDuration, entry.attempts))
 	}
-
-	return shares, nil
 }
 
-// GetShares retrieves shares from configured paths.
+// GetShares retrieves shares from the configured locations, retrying unreachable ones
+// until the threshold is met or ctx is done. Shares in seed are counted toward the
+// threshold before any location is fetched.
 func (s *Service) GetShares(
-	paths []string,
+	ctx context.Context,
+	locations []config.Location,
+	maxConcurrency int,
 	appState state.State,
 	retryInterval uint16,
 	serverTimeout uint16,
 	test bool,
 	unraidSvc unraidVerifier,
+	seed []*keys.KeyShare,
 ) ([]*keys.KeyShare, error) {
 	retryDuration := time.Duration(retryInterval) * time.Second
 	serverTimeoutDuration := time.Duration(serverTimeout) * time.Second
 
-	logSharePaths(paths)
+	logSharePaths(locations)
 
 	shares, err := s.collectShares(
-		paths,
+		ctx,
+		locations,
+		maxConcurrency,
 		appState,
 		retryDuration,
 		serverTimeoutDuration,
 		test,
 		unraidSvc,
+		seed,
 	)
 	if err != nil {
 		return nil, err
@@ -285,9 +571,21 @@ func (s *Service) GetShares(
 	)
 }
 
-func logSharePaths(paths []string) {
-	for i, path := range paths {
-		log.Debug().Int("path", i).Str("target", path).Msg("Configured share path")
+func logSharePaths(locations []config.Location) {
+	for i, location := range locations {
+		for mirror, target := range location.Mirrors() {
+			log.Debug().
+				Int("path", i).
+				Int("mirror", mirror).
+				Str("name", location.Name).
+				Str("group", location.Group).
+				Int("priority", location.Priority).
+				Dur("timeout", time.Duration(location.Timeout)).
+				Dur("backoff", time.Duration(location.Backoff)).
+				Int("max-attempts", location.MaxAttempts).
+				Str("target", target).
+				Msg("Configured share path")
+		}
 	}
 }
 
@@ -0,0 +1,483 @@
+package secrets
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bytemare/secret-sharing/keys"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/state"
+	"github.com/spf13/afero"
+)
+
+// Testing objectives:
+// - Verify that SplitMirrors splits fallback chains and ignores empty entries.
+// - Verify that mirrors are tried in order and the serving mirror is reported.
+// - Ensure that an unreachable mirror leaves the line to be retried, but corrupt shares do not.
+// - Verify that collectShares treats a fallback chain as one logical location.
+// - Verify that seed shares count toward the threshold and are not collected twice.
+// - Verify that ReadConfig accepts both the legacy and structured formats.
+// - Verify that a location's timeout and params are applied to its fetches.
+// - Ensure that a hanging mirror does not use up the timeout of the mirrors after it.
+// - Ensure that retries stop at max-attempts and that collection stops at the deadline.
+// - Verify that fetches still in flight are cancelled once the threshold is reached.
+// - Verify that a higher priority location is only fetched when the lower ones fall short.
+// - Ensure that a cancelled fetch-share subprocess gets SIGTERM and can clean up.
+
+// fakeLocations serves shares by location and records the order of fetches.
+type fakeLocations struct {
+	mutex   sync.Mutex
+	shares  map[string]string
+	fetched []string
+}
+
+func (f *fakeLocations) fetch(_ context.Context, path string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.fetched = append(f.fetched, path)
+
+	share, found := f.shares[path]
+	if !found {
+		return "", errors.New("unreachable")
+	}
+
+	return share, nil
+}
+
+func newTestService(t *testing.T, locations *fakeLocations) (*Service, SharedSecret) {
+	t.Helper()
+
+	svc := NewService(afero.NewMemMapFs(), "", false, false)
+	svc.fetch = locations.fetch
+
+	secret, err := svc.CreateSecret(2, 3)
+	if err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+
+	return svc, secret
+}
+
+// chain returns the location for a legacy configuration line.
+func chain(line string) config.Location {
+	return LocationsFromPaths([]string{line})[0]
+}
+
+func encodeShare(secret SharedSecret, index int) string {
+	return base64.StdEncoding.EncodeToString(secret.Shares[index])
+}
+
+// TestSplitMirrors tests splitting configuration lines into mirrors.
+func TestSplitMirrors(t *testing.T) {
+	testCases := map[string][]string{
+		"https://a/share":                        {"https://a/share"},
+		"https://a/share || :sftp,host=b:/share": {"https://a/share", ":sftp,host=b:/share"},
+		"https://a/share||dns:b.example.com|| ":  {"https://a/share", "dns:b.example.com"},
+		"  ||  ":                                 nil,
+	}
+
+	for line, want := range testCases {
+		got := SplitMirrors(line)
+		if !slices.Equal(got, want) {
+			t.Errorf("SplitMirrors(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+// TestTryGetShare_Mirrors tests trying mirrors in order.
+func TestTryGetShare_Mirrors(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{
+		"corrupt": "not-a-share",
+		"mirror":  encodeShare(secret, 0),
+	}
+
+	retrieved, settled, err := svc.tryGetShare(t.Context(), chain("primary || corrupt || mirror"), 0, secret.SigningKey, time.Second)
+	if err != nil {
+		t.Fatalf("tryGetShare failed: %v", err)
+	}
+
+	if !settled || retrieved.Mirror != 2 || retrieved.Share == nil {
+		t.Errorf("unexpected result: settled=%v mirror=%d", settled, retrieved.Mirror)
+	}
+
+	if !slices.Equal(locations.fetched, []string{"primary", "corrupt", "mirror"}) {
+		t.Errorf("fetch order = %q", locations.fetched)
+	}
+}
+
+// TestTryGetShare_MirrorTimeout tests that each mirror gets its own timeout.
+func TestTryGetShare_MirrorTimeout(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{"mirror": encodeShare(secret, 0)}
+
+	svc.fetch = func(ctx context.Context, path string) (string, error) {
+		if path == "hanging" {
+			<-ctx.Done()
+
+			return "", ctx.Err()
+		}
+
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		return locations.fetch(ctx, path)
+	}
+
+	retrieved, _, err := svc.tryGetShare(t.Context(), chain("hanging || mirror"), 0, secret.SigningKey, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("tryGetShare failed: %v", err)
+	}
+
+	if retrieved.Mirror != 1 {
+		t.Errorf("mirror = %d, want 1", retrieved.Mirror)
+	}
+}
+
+// TestTryGetShare_Failures tests which failures leave the line to be retried.
+func TestTryGetShare_Failures(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{"corrupt": "not-a-share"}
+
+	_, settled, err := svc.tryGetShare(t.Context(), chain("corrupt || down"), 0, secret.SigningKey, time.Second)
+	if err == nil || settled {
+		t.Errorf("unreachable mirror: settled=%v err=%v, want retry", settled, err)
+	}
+
+	_, settled, err = svc.tryGetShare(t.Context(), chain("corrupt"), 0, secret.SigningKey, time.Second)
+	if err == nil || !settled {
+		t.Errorf("corrupt share: settled=%v err=%v, want no retry", settled, err)
+	}
+}
+
+// TestCollectShares_FallbackChain tests that a chain counts as one location.
+func TestCollectShares_FallbackChain(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{
+		"primary-a": encodeShare(secret, 0),
+		"mirror-a":  encodeShare(secret, 0),
+		"mirror-b":  encodeShare(secret, 1),
+	}
+
+	appState := state.State{SigningKey: secret.SigningKey, Threshold: 2}
+
+	shares, err := svc.collectShares(
+		t.Context(),
+		LocationsFromPaths([]string{"primary-a || mirror-a", "primary-b || mirror-b"}),
+		0,
+		appState,
+		time.Millisecond,
+		time.Second,
+		false,
+		nil,
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("collectShares failed: %v", err)
+	}
+
+	if len(shares) != 2 {
+		t.Errorf("collected %d shares, want 2", len(shares))
+	}
+
+	if slices.Contains(locations.fetched, "mirror-a") {
+		t.Error("mirror-a was fetched although primary-a served the share")
+	}
+}
+
+// TestCollectShares_Seed tests counting seed shares toward the threshold.
+func TestCollectShares_Seed(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{
+		"copy-of-seed": encodeShare(secret, 2),
+		"other":        encodeShare(secret, 0),
+	}
+
+	seed, err := svc.GetShare(encodeShare(secret, 2), secret.SigningKey)
+	if err != nil {
+		t.Fatalf("GetShare failed: %v", err)
+	}
+
+	appState := state.State{SigningKey: secret.SigningKey, Threshold: 2}
+	paths := LocationsFromPaths([]string{"copy-of-seed", "other"})
+
+	shares, err := svc.collectShares(t.Context(), paths, 0, appState, time.Millisecond, time.Second, false, nil, []*keys.KeyShare{seed})
+	if err != nil {
+		t.Fatalf("collectShares failed: %v", err)
+	}
+
+	if len(shares) != 2 {
+		t.Fatalf("collected %d shares, want 2", len(shares))
+	}
+
+	if shares[1].Identifier() == seed.Identifier() {
+		t.Error("copy of the seed share was collected again")
+	}
+
+	// A seed that already meets the threshold needs no fetches.
+	locations.fetched = nil
+	appState.Threshold = 1
+
+	shares, err = svc.collectShares(t.Context(), paths, 0, appState, time.Millisecond, time.Second, false, nil, []*keys.KeyShare{seed})
+	if err != nil || len(shares) != 1 || len(locations.fetched) != 0 {
+		t.Errorf("shares=%d fetched=%q err=%v, want seed only", len(shares), locations.fetched, err)
+	}
+}
+
+// TestReadConfig tests reading legacy and structured configuration files.
+func TestReadConfig(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	svc := NewService(fs, "", false, false)
+
+	_ = afero.WriteFile(fs, "/legacy.txt", []byte("# comment\nhttps://a/share || dns:b\n\n:sftp,host=c:/share\n"), 0o600)
+	_ = afero.WriteFile(fs, "/config.txt", []byte("locations:\n  - name: nas\n    url: https://a/share\n"), 0o600)
+
+	cfg, format, err := svc.ReadConfig("/legacy.txt")
+	if err != nil {
+		t.Fatalf("ReadConfig failed: %v", err)
+	}
+
+	if format != config.FormatLegacy || len(cfg.Locations) != 2 ||
+		!slices.Equal(cfg.Locations[0].Mirrors(), []string{"https://a/share", "dns:b"}) {
+		t.Errorf("unexpected legacy config: %s %+v", format, cfg)
+	}
+
+	cfg, format, err = svc.ReadConfig("/config.txt")
+	if err != nil {
+		t.Fatalf("ReadConfig failed: %v", err)
+	}
+
+	if format != config.FormatYAML || len(cfg.Locations) != 1 || cfg.Locations[0].Name != "nas" {
+		t.Errorf("unexpected structured config: %s %+v", format, cfg)
+	}
+
+	_, _, err = svc.ReadConfig("/missing.txt")
+	if err == nil {
+		t.Error("expected error for missing file, got none")
+	}
+}
+
+// TestTryGetShare_LocationOptions tests applying a location's timeout and params.
+func TestTryGetShare_LocationOptions(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{"https://nas/share?token=abc": encodeShare(secret, 0)}
+
+	var remaining time.Duration
+
+	svc.fetch = func(ctx context.Context, path string) (string, error) {
+		deadline, _ := ctx.Deadline()
+		remaining = time.Until(deadline)
+
+		return locations.fetch(ctx, path)
+	}
+
+	location := config.Location{
+		URL:     "https://nas/share",
+		Options: config.Options{Timeout: config.Duration(time.Second), Params: map[string]string{"token": "abc"}},
+	}
+
+	_, _, err := svc.tryGetShare(t.Context(), location, 0, secret.SigningKey, time.Minute)
+	if err != nil {
+		t.Fatalf("tryGetShare failed: %v", err)
+	}
+
+	if remaining > time.Second {
+		t.Errorf("fetch had %v left, want the location timeout of 1s", remaining)
+	}
+
+	// Params for a fetcher that does not accept them are a configuration error, not
+	// an unreachable location.
+	location = config.Location{URL: "dns:share.example.com", Options: config.Options{Params: map[string]string{"a": "b"}}}
+
+	_, settled, err := svc.tryGetShare(t.Context(), location, 0, secret.SigningKey, time.Second)
+	if err == nil || !settled {
+		t.Errorf("settled=%v err=%v, want settled error", settled, err)
+	}
+}
+
+// TestCollectShares_MaxAttempts tests giving up on a location after its attempts.
+func TestCollectShares_MaxAttempts(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+
+	appState := state.State{SigningKey: secret.SigningKey, Threshold: 2}
+	down := config.Location{
+		URL:     "down",
+		Options: config.Options{Backoff: config.Duration(time.Millisecond), MaxAttempts: 3},
+	}
+
+	shares, err := svc.collectShares(t.Context(), []config.Location{down}, 0, appState, time.Hour, time.Second, false, nil, nil)
+	if err != nil || len(shares) != 0 {
+		t.Fatalf("shares=%d err=%v, want none without error", len(shares), err)
+	}
+
+	if len(locations.fetched) != 3 {
+		t.Errorf("fetched %d times, want 3", len(locations.fetched))
+	}
+}
+
+// TestCollectShares_Deadline tests that waiting for a retry ends at the deadline.
+func TestCollectShares_Deadline(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+
+	appState := state.State{SigningKey: secret.SigningKey, Threshold: 2}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := svc.collectShares(ctx, LocationsFromPaths([]string{"down"}), 0, appState, time.Hour, time.Second, false, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("collectShares took %v, expected to stop at the deadline", time.Since(start))
+	}
+}
+
+// TestCollectShares_CancelsInFlight tests that a hanging location does not delay the result.
+func TestCollectShares_CancelsInFlight(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{
+		"fast-a": encodeShare(secret, 0),
+		"fast-b": encodeShare(secret, 1),
+	}
+
+	cancelled := make(chan struct{})
+
+	svc.fetch = func(ctx context.Context, path string) (string, error) {
+		if path == "hanging" {
+			<-ctx.Done()
+			close(cancelled)
+
+			return "", ctx.Err()
+		}
+
+		return locations.fetch(ctx, path)
+	}
+
+	appState := state.State{SigningKey: secret.SigningKey, Threshold: 2}
+	paths := LocationsFromPaths([]string{"hanging", "fast-a", "fast-b"})
+	start := time.Now()
+
+	shares, err := svc.collectShares(t.Context(), paths, 0, appState, time.Hour, time.Minute, false, nil, nil)
+	if err != nil || len(shares) != 2 {
+		t.Fatalf("shares=%d err=%v, want 2 shares", len(shares), err)
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("collectShares took %v, expected not to wait for the hanging location", time.Since(start))
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Error("hanging fetch was not cancelled")
+	}
+}
+
+// TestCollectShares_Priority tests that the cloud location is only used as a backup.
+func TestCollectShares_Priority(t *testing.T) {
+	locations := &fakeLocations{}
+	svc, secret := newTestService(t, locations)
+	locations.shares = map[string]string{
+		"lan-a": encodeShare(secret, 0),
+		"lan-b": encodeShare(secret, 1),
+		"cloud": encodeShare(secret, 2),
+	}
+
+	appState := state.State{SigningKey: secret.SigningKey, Threshold: 2}
+	paths := LocationsFromPaths([]string{"cloud", "lan-a", "lan-b"})
+	paths[0].Priority = 10
+
+	shares, err := svc.collectShares(t.Context(), paths, 1, appState, time.Hour, time.Second, false, nil, nil)
+	if err != nil || len(shares) != 2 {
+		t.Fatalf("shares=%d err=%v, want 2 shares", len(shares), err)
+	}
+
+	if !slices.Equal(locations.fetched, []string{"lan-a", "lan-b"}) {
+		t.Errorf("fetched %v, want only the LAN locations in order", locations.fetched)
+	}
+
+	// With a LAN location down, the cloud location makes up the difference.
+	delete(locations.shares, "lan-b")
+
+	locations.fetched = nil
+
+	shares, err = svc.collectShares(t.Context(), paths, 1, appState, time.Hour, time.Second, false, nil, nil)
+	if err != nil || len(shares) != 2 {
+		t.Fatalf("shares=%d err=%v, want 2 shares", len(shares), err)
+	}
+
+	if !slices.Equal(locations.fetched, []string{"lan-a", "lan-b", "cloud"}) {
+		t.Errorf("fetched %v, want the cloud location last", locations.fetched)
+	}
+}
+
+// TestFetchShare_Terminate tests that cancelling a fetch lets the subprocess clean up.
+func TestFetchShare_Terminate(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "cleaned")
+	script := filepath.Join(dir, "fetch-share")
+
+	err := os.WriteFile(
+		script,
+		[]byte("#!/bin/sh\ntrap 'echo > "+marker+"; exit 1' TERM\nwhile :; do sleep 0.05; done\n"),
+		0o700,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewService(afero.NewMemMapFs(), script, false, false)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err = svc.fetchShare(ctx, "usb:LABEL=KEY:/share")
+	if err == nil {
+		t.Fatal("expected error from cancelled fetch")
+	}
+
+	_, err = os.Stat(marker)
+	if err != nil {
+		t.Errorf("subprocess did not clean up: %v", err)
+	}
+}
@@ -22,8 +22,12 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/httpsig"
+	"github.com/spf13/afero"
 )
 
 // TestFetch_Success tests successful HTTP GET requests.
@@ -466,3 +470,65 @@ func TestParseURL_InsecureWithAuth(t *testing.T) {
 		t.Error("Expected user info in URL, got none")
 	}
 }
+
+// TestFetch_SignedRequest tests that requests are signed with the device key when sig-key is set.
+func TestFetch_SignedRequest(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "device.key")
+
+	pub, _, err := httpsig.EnsureKeyFile(afero.NewOsFs(), keyPath)
+	if err != nil {
+		t.Fatalf("EnsureKeyFile failed: %v", err)
+	}
+
+	verifier := httpsig.NewVerifier(pub)
+
+	server := httptest.NewServer(verifier.Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte("signed-content"))
+		}),
+	))
+	defer server.Close()
+
+	ctx := context.Background()
+	fetcher := &Fetcher{}
+
+	_, err = fetcher.Fetch(ctx, server.URL+"/share")
+	if err == nil {
+		t.Error("Expected unsigned request to be rejected, got none")
+	}
+
+	got, err := fetcher.Fetch(ctx, server.URL+"/share?sig-key="+keyPath)
+	if err != nil {
+		t.Fatalf("Fetch with signature failed: %v", err)
+	}
+
+	if got != "signed-content" {
+		t.Errorf("Fetch() = %q, want %q", got, "signed-content")
+	}
+
+	// Without a key file, the device key given with --devicekey signs the request.
+	_, err = fetcher.Fetch(httpsig.WithKeyFile(ctx, keyPath), server.URL+"/share?sig-key")
+	if err != nil {
+		t.Errorf("Fetch with the configured device key failed: %v", err)
+	}
+}
+
+// TestValidate_Signature tests that Validate checks the signing options.
+func TestValidate_Signature(t *testing.T) {
+	testCases := map[string]bool{
+		"https://nas/share?sig-key=/boot/device.key":       true,
+		"https://nas/share?sig-key":                        true,
+		"https://nas/share?sig-keyid=nas":                  true,
+		"https://nas/share?sig-key=device.key&sig-keyid=x": false,
+	}
+
+	fetcher := &Fetcher{}
+
+	for path, valid := range testCases {
+		err := fetcher.Validate(path)
+		if (err == nil) != valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", path, err, valid)
+		}
+	}
+
+}
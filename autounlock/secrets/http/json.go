@@ -0,0 +1,208 @@
+package http
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// paramMaxSize is reserved for overriding the response size limit.
+	paramMaxSize = "max-size"
+
+	// defaultJSONResponseSize is the size limit when a JSON selector is used,
+	// since API responses usually carry more than the bare share.
+	defaultJSONResponseSize = 64 * 1024
+	// maxResponseSizeLimit is the largest value accepted for max-size.
+	maxResponseSizeLimit = 1024 * 1024
+)
+
+// responseOptions describes how the response body is turned into a share.
+type responseOptions struct {
+	// Selector is a JSONPath expression (e.g. $.data.share). Empty means the body is the share.
+	Selector []pathSegment
+	// MaxSize is the maximum number of bytes read from the response body.
+	MaxSize int64
+}
+
+// pathSegment is a single step in a JSONPath selector: either an object key or an array index.
+type pathSegment struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// parseResponseOptions extracts the JSON selector from the URL fragment and the
+// max-size query parameter, removing both from the URL in place.
+func parseResponseOptions(parsedURL *url.URL) (responseOptions, error) {
+	opts := responseOptions{MaxSize: maxResponseSize}
+
+	if parsedURL.Fragment != "" {
+		selector, err := parseSelector(parsedURL.Fragment)
+		if err != nil {
+			return responseOptions{}, err
+		}
+
+		opts.Selector = selector
+		opts.MaxSize = defaultJSONResponseSize
+		parsedURL.Fragment = ""
+		parsedURL.RawFragment = ""
+	}
+
+	query := parsedURL.Query()
+	if query.Has(paramMaxSize) {
+		size, err := strconv.ParseInt(query.Get(paramMaxSize), 10, 64)
+		if err != nil || size <= 0 || size > maxResponseSizeLimit {
+			return responseOptions{}, fmt.Errorf(
+				"invalid %s: must be between 1 and %d",
+				paramMaxSize,
+				maxResponseSizeLimit,
+			)
+		}
+
+		opts.MaxSize = size
+
+		query.Del(paramMaxSize)
+		parsedURL.RawQuery = query.Encode()
+	}
+
+	return opts, nil
+}
+
+// parseSelector parses a JSONPath subset: $ followed by .key, ['key'], ["key"] or [index].
+func parseSelector(selector string) ([]pathSegment, error) {
+	if !strings.HasPrefix(selector, "$") {
+		return nil, fmt.Errorf("JSON selector must start with $: %s", selector)
+	}
+
+	rest := selector[1:]
+
+	var segments []pathSegment
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in JSON selector: %s", selector)
+			}
+
+			segments = append(segments, pathSegment{key: rest[:end], isKey: true})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated bracket in JSON selector: %s", selector)
+			}
+
+			segment, err := parseBracket(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON selector %s: %w", selector, err)
+			}
+
+			segments = append(segments, segment)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected character %q in JSON selector: %s", rest[0], selector)
+		}
+	}
+
+	return segments, nil
+}
+
+func parseBracket(content string) (pathSegment, error) {
+	if len(content) >= 2 &&
+		(content[0] == '\'' || content[0] == '"') &&
+		content[len(content)-1] == content[0] {
+		return pathSegment{key: content[1 : len(content)-1], isKey: true}, nil
+	}
+
+	index, err := strconv.Atoi(content)
+	if err != nil || index < 0 {
+		return pathSegment{}, fmt.Errorf("invalid index %q", content)
+	}
+
+	return pathSegment{index: index}, nil
+}
+
+// extractJSON applies the selector to a JSON document and returns the selected string value.
+func extractJSON(data []byte, selector []pathSegment) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var current any
+
+	err := decoder.Decode(&current)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	for _, segment := range selector {
+		current, err = segment.apply(current)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	value, ok := current.(string)
+	if !ok {
+		return "", errors.New("JSON selector did not resolve to a string")
+	}
+
+	return value, nil
+}
+
+func (p pathSegment) apply(value any) (any, error) {
+	if p.isKey {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot select key %q from non-object", p.key)
+		}
+
+		child, found := object[p.key]
+		if !found {
+			return nil, fmt.Errorf("key %q not found in JSON response", p.key)
+		}
+
+		return child, nil
+	}
+
+	array, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot select index %d from non-array", p.index)
+	}
+
+	if p.index >= len(array) {
+		return nil, fmt.Errorf("index %d out of range in JSON response", p.index)
+	}
+
+	return array[p.index], nil
+}
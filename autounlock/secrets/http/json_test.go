@@ -0,0 +1,197 @@
+package http
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Testing objectives:
+// - Verify that a JSON selector in the fragment extracts the share from the response.
+// - Ensure that selectors not resolving to a single string are rejected.
+// - Verify that max-size overrides the response size limit.
+// - Verify parsing of the selector and size limit options.
+
+// TestFetch_JSONSelector tests extracting a share from a JSON response.
+func TestFetch_JSONSelector(t *testing.T) {
+	body := `{"data": {"items": [{"share": "first"}, {"share": " second "}], "share": "top"}}`
+
+	testCases := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{"dot notation", "$.data.share", "top"},
+		{"array index", "$.data.items[0].share", "first"},
+		{"bracket key", "$['data']['items'][1]['share']", "second"},
+		{"double quoted key", `$["data"].share`, "top"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has(paramMaxSize) {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fetcher := &Fetcher{}
+
+			got, err := fetcher.Fetch(context.Background(), server.URL+"/api#"+tc.fragment)
+			if err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("Fetch() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestFetch_JSONSelectorErrors tests selectors that do not resolve to a string.
+func TestFetch_JSONSelectorErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		fragment string
+	}{
+		{"missing key", `{"data": {}}`, "$.data.share"},
+		{"non-string value", `{"share": 42}`, "$.share"},
+		{"object value", `{"share": {"a": "b"}}`, "$.share"},
+		{"index out of range", `{"items": []}`, "$.items[0]"},
+		{"key on array", `[1, 2]`, "$.share"},
+		{"invalid JSON", `not json`, "$.share"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(tc.body))
+				}),
+			)
+			defer server.Close()
+
+			fetcher := &Fetcher{}
+
+			_, err := fetcher.Fetch(context.Background(), server.URL+"#"+tc.fragment)
+			if err == nil {
+				t.Errorf("Expected error for selector %q on %s, got none", tc.fragment, tc.body)
+			}
+		})
+	}
+}
+
+// TestFetch_MaxSize tests that max-size overrides the response size limit.
+func TestFetch_MaxSize(t *testing.T) {
+	body := strings.Repeat("A", maxResponseSize*2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has(paramMaxSize) {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	fetcher := &Fetcher{}
+
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+	if err == nil || !strings.Contains(err.Error(), "response body too large") {
+		t.Fatalf("Expected size limit error with default limit, got: %v", err)
+	}
+
+	got, err := fetcher.Fetch(context.Background(), server.URL+"?max-size=2048")
+	if err != nil {
+		t.Fatalf("Fetch with max-size failed: %v", err)
+	}
+
+	if got != body {
+		t.Errorf("Fetch() returned %d bytes, want %d bytes", len(got), len(body))
+	}
+}
+
+// TestParseResponseOptions tests parsing of the selector and size limit.
+func TestParseResponseOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		wantSize int64
+		wantErr  bool
+	}{
+		{"defaults", "https://example.com/share", maxResponseSize, false},
+		{"selector raises default", "https://example.com/api#$.share", defaultJSONResponseSize, false},
+		{"explicit size", "https://example.com/api?max-size=100#$.share", 100, false},
+		{"size at limit", "https://example.com/?max-size=1048576", maxResponseSizeLimit, false},
+		{"size over limit", "https://example.com/?max-size=1048577", 0, true},
+		{"zero size", "https://example.com/?max-size=0", 0, true},
+		{"non-numeric size", "https://example.com/?max-size=big", 0, true},
+		{"selector without $", "https://example.com/#data.share", 0, true},
+		{"empty key", "https://example.com/#$..share", 0, true},
+		{"unterminated bracket", "https://example.com/#$.items[0", 0, true},
+		{"negative index", "https://example.com/#$.items[-1]", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parsedURL, _, err := parseURL(tc.url)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %v", err)
+			}
+
+			opts, err := parseResponseOptions(parsedURL)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got none", tc.url)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if opts.MaxSize != tc.wantSize {
+				t.Errorf("MaxSize = %d, want %d", opts.MaxSize, tc.wantSize)
+			}
+
+			if parsedURL.Fragment != "" || parsedURL.Query().Has(paramMaxSize) {
+				t.Errorf("Reserved options were not removed from URL: %s", parsedURL)
+			}
+		})
+	}
+}
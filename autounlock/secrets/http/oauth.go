@@ -0,0 +1,370 @@
+package http
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Query parameters reserved for OAuth2 client-credentials configuration.
+const (
+	paramOAuthTokenURL     = "oauth-token-url"
+	paramOAuthClientID     = "oauth-client-id"
+	paramOAuthClientSecret = "oauth-client-secret"
+	paramOAuthPrivateKey   = "oauth-private-key"
+	paramOAuthKeyID        = "oauth-key-id"
+	paramOAuthScope        = "oauth-scope"
+	paramOAuthAudience     = "oauth-audience"
+
+	clientAssertionType  = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	clientAssertionTTL   = time.Minute
+	maxTokenResponseSize = 64 * 1024
+	ecdsaP256Size        = 32
+	jwtIDBytes           = 16
+)
+
+// oauthOptions describes an OAuth2 client-credentials grant used to obtain a bearer token.
+// Either ClientSecret (client_secret_basic) or PrivateKeyFile (private_key_jwt) is used.
+type oauthOptions struct {
+	TokenURL       string
+	ClientID       string
+	ClientSecret   string
+	PrivateKeyFile string
+	KeyID          string
+	Scope          string
+	Audience       string
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// parseOAuthOptions extracts the reserved OAuth2 query parameters from the URL, removing them in place.
+// Returns nil if no token endpoint is configured.
+func parseOAuthOptions(parsedURL *url.URL) (*oauthOptions, error) {
+	query := parsedURL.Query()
+
+	params := []string{
+		paramOAuthTokenURL,
+		paramOAuthClientID,
+		paramOAuthClientSecret,
+		paramOAuthPrivateKey,
+		paramOAuthKeyID,
+		paramOAuthScope,
+		paramOAuthAudience,
+	}
+
+	reserved := false
+
+	for _, name := range params {
+		if query.Has(name) {
+			reserved = true
+		}
+	}
+
+	if !reserved {
+		return nil, nil //nolint:nilnil // No OAuth configuration is not an error
+	}
+
+	opts := &oauthOptions{
+		TokenURL:       query.Get(paramOAuthTokenURL),
+		ClientID:       query.Get(paramOAuthClientID),
+		ClientSecret:   query.Get(paramOAuthClientSecret),
+		PrivateKeyFile: query.Get(paramOAuthPrivateKey),
+		KeyID:          query.Get(paramOAuthKeyID),
+		Scope:          query.Get(paramOAuthScope),
+		Audience:       query.Get(paramOAuthAudience),
+	}
+
+	for _, name := range params {
+		query.Del(name)
+	}
+
+	parsedURL.RawQuery = query.Encode()
+
+	err := opts.validate(parsedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+func (o *oauthOptions) validate(parsedURL *url.URL) error {
+	if o.TokenURL == "" {
+		return errors.New("oauth-token-url is required for OAuth2")
+	}
+
+	tokenURL, err := url.Parse(o.TokenURL)
+	if err != nil || (tokenURL.Scheme != "https" && tokenURL.Scheme != "http") {
+		return fmt.Errorf("invalid oauth-token-url: %s", o.TokenURL)
+	}
+
+	if o.ClientID == "" {
+		return errors.New("oauth-client-id is required for OAuth2")
+	}
+
+	if (o.ClientSecret == "") == (o.PrivateKeyFile == "") {
+		return errors.New("exactly one of oauth-client-secret or oauth-private-key is required")
+	}
+
+	if parsedURL.User != nil {
+		return errors.New("basic auth credentials cannot be combined with OAuth2")
+	}
+
+	return nil
+}
+
+// fetchToken performs the client-credentials grant and returns the access token.
+// The token is used for a single request and never written to disk.
+func (o *oauthOptions) fetchToken(ctx context.Context, client Client) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+
+	if o.Scope != "" {
+		form.Set("scope", o.Scope)
+	}
+
+	if o.Audience != "" {
+		form.Set("audience", o.Audience)
+	}
+
+	if o.PrivateKeyFile != "" {
+		assertion, err := o.clientAssertion()
+		if err != nil {
+			return "", err
+		}
+
+		form.Set("client_id", o.ClientID)
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		o.TokenURL,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if o.ClientSecret != "" {
+		// RFC 6749 section 2.3.1 requires form-encoding the credentials before basic auth.
+		req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status: %d", resp.StatusCode)
+	}
+
+	var token tokenResponse
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxTokenResponseSize)).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	if token.AccessToken == "" {
+		return "", errors.New("token response did not contain an access token")
+	}
+
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return "", fmt.Errorf("unsupported token type: %s", token.TokenType)
+	}
+
+	return token.AccessToken, nil
+}
+
+// clientAssertion builds a signed JWT for private_key_jwt client authentication (RFC 7523).
+func (o *oauthOptions) clientAssertion() (string, error) {
+	signer, err := loadPrivateKey(o.PrivateKeyFile)
+	if err != nil {
+		return "", err
+	}
+
+	alg, err := jwtAlgorithm(signer)
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if o.KeyID != "" {
+		header["kid"] = o.KeyID
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss": o.ClientID,
+		"sub": o.ClientID,
+		"aud": o.TokenURL,
+		"jti": jti,
+		"iat": now.Unix(),
+		"exp": now.Add(clientAssertionTTL).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT header: %w", err)
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	signature, err := signJWT(signer, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// loadPrivateKey reads a PEM encoded PKCS#8, PKCS#1 or SEC 1 private key.
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in private key: %s", path)
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+
+		return signer, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("failed to parse private key: %s", path)
+}
+
+func jwtAlgorithm(signer crypto.Signer) (string, error) {
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		return "RS256", nil
+	case *ecdsa.PrivateKey:
+		if key.Curve.Params().BitSize != ecdsaP256Size*8 {
+			return "", errors.New("only P-256 ECDSA keys are supported")
+		}
+
+		return "ES256", nil
+	case ed25519.PrivateKey:
+		return "EdDSA", nil
+	default:
+		return "", errors.New("unsupported private key type")
+	}
+}
+
+func signJWT(signer crypto.Signer, signingInput []byte) ([]byte, error) {
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		signature, err := signer.Sign(rand.Reader, signingInput, crypto.Hash(0))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign JWT: %w", err)
+		}
+
+		return signature, nil
+	}
+
+	digest := sha256.Sum256(signingInput)
+
+	if key, ok := signer.(*ecdsa.PrivateKey); ok {
+		// JWS uses the fixed-width r||s encoding rather than ASN.1.
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign JWT: %w", err)
+		}
+
+		signature := make([]byte, 2*ecdsaP256Size)
+		r.FillBytes(signature[:ecdsaP256Size])
+		s.FillBytes(signature[ecdsaP256Size:])
+
+		return signature, nil
+	}
+
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return signature, nil
+}
+
+func randomID() (string, error) {
+	id := make([]byte, jwtIDBytes)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate JWT ID: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
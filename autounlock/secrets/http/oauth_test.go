@@ -0,0 +1,341 @@
+package http
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Testing objectives:
+// - Verify the OAuth2 client credentials grant with client_secret_basic.
+// - Ensure that a rejected token request fails the fetch.
+// - Verify private_key_jwt client authentication with RSA, ECDSA and Ed25519 keys.
+// - Verify parsing and validation of the OAuth2 parameters.
+
+const testAccessToken = "test-access-token"
+
+// newTokenServer creates a local stand-in for an OAuth2 token endpoint.
+// authenticate validates the client authentication of each token request.
+func newTokenServer(t *testing.T, authenticate func(r *http.Request) bool) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		err := r.ParseForm()
+		if err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		if !authenticate(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"` + testAccessToken + `","token_type":"Bearer"}`))
+	}))
+}
+
+// newBearerServer creates a share server that requires the test access token.
+func newBearerServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testAccessToken {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		if strings.Contains(r.URL.RawQuery, "oauth-") {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		w.Write([]byte("oauth-content"))
+	}))
+}
+
+// TestFetch_OAuthClientSecret tests the client credentials grant with client_secret_basic.
+func TestFetch_OAuthClientSecret(t *testing.T) {
+	tokenServer := newTokenServer(t, func(r *http.Request) bool {
+		user, pass, ok := r.BasicAuth()
+
+		return ok && user == "unraid%40box" && pass == "s3cret%2F" &&
+			r.PostForm.Get("scope") == "shares.read"
+	})
+	defer tokenServer.Close()
+
+	shareServer := newBearerServer(t)
+	defer shareServer.Close()
+
+	query := url.Values{}
+	query.Set(paramOAuthTokenURL, tokenServer.URL+"/token")
+	query.Set(paramOAuthClientID, "unraid@box")
+	query.Set(paramOAuthClientSecret, "s3cret/")
+	query.Set(paramOAuthScope, "shares.read")
+
+	fetcher := &Fetcher{}
+
+	got, err := fetcher.Fetch(context.Background(), shareServer.URL+"/share?"+query.Encode())
+	if err != nil {
+		t.Fatalf("Fetch with OAuth2 failed: %v", err)
+	}
+
+	if got != "oauth-content" {
+		t.Errorf("Fetch() = %q, want %q", got, "oauth-content")
+	}
+}
+
+// TestFetch_OAuthTokenRejected tests that a failed token request fails the fetch.
+func TestFetch_OAuthTokenRejected(t *testing.T) {
+	tokenServer := newTokenServer(t, func(*http.Request) bool { return false })
+	defer tokenServer.Close()
+
+	shareServer := newBearerServer(t)
+	defer shareServer.Close()
+
+	query := url.Values{}
+	query.Set(paramOAuthTokenURL, tokenServer.URL)
+	query.Set(paramOAuthClientID, "id")
+	query.Set(paramOAuthClientSecret, "wrong")
+
+	fetcher := &Fetcher{}
+
+	_, err := fetcher.Fetch(context.Background(), shareServer.URL+"?"+query.Encode())
+	if err == nil {
+		t.Fatal("Expected error for rejected token request, got none")
+	}
+
+	if !strings.Contains(err.Error(), "failed to obtain OAuth2 token") {
+		t.Errorf("Expected token error, got: %v", err)
+	}
+}
+
+// TestFetch_OAuthPrivateKeyJWT tests private_key_jwt client authentication with each key type.
+func TestFetch_OAuthPrivateKeyJWT(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	testCases := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{"ES256", ecKey, "ES256"},
+		{"RS256", rsaKey, "RS256"},
+		{"EdDSA", edKey, "EdDSA"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			der, err := x509.MarshalPKCS8PrivateKey(tc.key)
+			if err != nil {
+				t.Fatalf("failed to marshal key: %v", err)
+			}
+
+			keyPath := writePEM(t, t.TempDir(), "oauth.key", "PRIVATE KEY", der)
+
+			var tokenURL string
+
+			tokenServer := newTokenServer(t, func(r *http.Request) bool {
+				return r.PostForm.Get("client_assertion_type") == clientAssertionType &&
+					r.PostForm.Get("client_id") == "unraid" &&
+					verifyAssertion(t, r.PostForm.Get("client_assertion"), tc.key.Public(), tc.alg, tokenURL)
+			})
+			defer tokenServer.Close()
+
+			tokenURL = tokenServer.URL + "/token"
+
+			shareServer := newBearerServer(t)
+			defer shareServer.Close()
+
+			query := url.Values{}
+			query.Set(paramOAuthTokenURL, tokenURL)
+			query.Set(paramOAuthClientID, "unraid")
+			query.Set(paramOAuthPrivateKey, keyPath)
+			query.Set(paramOAuthKeyID, "key-1")
+
+			fetcher := &Fetcher{}
+
+			got, err := fetcher.Fetch(context.Background(), shareServer.URL+"?"+query.Encode())
+			if err != nil {
+				t.Fatalf("Fetch with private_key_jwt failed: %v", err)
+			}
+
+			if got != "oauth-content" {
+				t.Errorf("Fetch() = %q, want %q", got, "oauth-content")
+			}
+		})
+	}
+}
+
+// verifyAssertion checks the signature and claims of a client assertion JWT.
+func verifyAssertion(t *testing.T, assertion string, pub crypto.PublicKey, alg string, aud string) bool {
+	t.Helper()
+
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	var header map[string]string
+
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if json.Unmarshal(headerJSON, &header) != nil || header["alg"] != alg || header["kid"] != "key-1" {
+		return false
+	}
+
+	var claims map[string]any
+
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if json.Unmarshal(claimsJSON, &claims) != nil ||
+		claims["iss"] != "unraid" || claims["sub"] != "unraid" || claims["aud"] != aud {
+		return false
+	}
+
+	signingInput := []byte(parts[0] + "." + parts[1])
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256(signingInput)
+
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+
+		return ecdsa.Verify(key, digest[:], r, s)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, signingInput, signature)
+	default:
+		return false
+	}
+}
+
+// TestParseOAuthOptions tests parsing and validation of OAuth2 parameters.
+func TestParseOAuthOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		url     string
+		wantNil bool
+		wantErr bool
+	}{
+		{"not configured", "https://example.com/share?id=1", true, false},
+		{
+			"client secret",
+			"https://example.com/?oauth-token-url=https://idp/token&oauth-client-id=a&oauth-client-secret=b",
+			false,
+			false,
+		},
+		{
+			"private key",
+			"https://example.com/?oauth-token-url=https://idp/token&oauth-client-id=a&oauth-private-key=/k.pem",
+			false,
+			false,
+		},
+		{"missing token url", "https://example.com/?oauth-client-id=a&oauth-client-secret=b", false, true},
+		{
+			"missing client id",
+			"https://example.com/?oauth-token-url=https://idp/token&oauth-client-secret=b",
+			false,
+			true,
+		},
+		{
+			"secret and key",
+			"https://example.com/?oauth-token-url=https://idp/token&oauth-client-id=a&oauth-client-secret=b&oauth-private-key=/k.pem",
+			false,
+			true,
+		},
+		{
+			"neither secret nor key",
+			"https://example.com/?oauth-token-url=https://idp/token&oauth-client-id=a",
+			false,
+			true,
+		},
+		{
+			"invalid token url",
+			"https://example.com/?oauth-token-url=ftp://idp/token&oauth-client-id=a&oauth-client-secret=b",
+			false,
+			true,
+		},
+		{
+			"combined with basic auth",
+			"https://u:p@example.com/?oauth-token-url=https://idp/token&oauth-client-id=a&oauth-client-secret=b",
+			false,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parsedURL, _, err := parseURL(tc.url)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %v", err)
+			}
+
+			opts, err := parseOAuthOptions(parsedURL)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got none", tc.url)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if (opts == nil) != tc.wantNil {
+				t.Errorf("opts = %+v, wantNil %v", opts, tc.wantNil)
+			}
+
+			if strings.Contains(parsedURL.RawQuery, "oauth-") {
+				t.Errorf("OAuth parameters were not removed: %s", parsedURL.RawQuery)
+			}
+		})
+	}
+}
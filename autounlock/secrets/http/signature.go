@@ -0,0 +1,92 @@
+package http
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/httpsig"
+)
+
+// Query parameters reserved for request signing.
+const (
+	paramSigKey   = "sig-key"
+	paramSigKeyID = "sig-keyid"
+)
+
+// signatureOptions selects the device key used to sign the share request.
+type signatureOptions struct {
+	KeyFile string
+	KeyID   string
+}
+
+// parseSignatureOptions extracts the reserved signing query parameters from the URL,
+// removing them in place. Returns nil if signing is not requested. Without a key file,
+// the device key given to autounlock with --devicekey is used.
+func parseSignatureOptions(parsedURL *url.URL) (*signatureOptions, error) {
+	query := parsedURL.Query()
+	if !query.Has(paramSigKey) && !query.Has(paramSigKeyID) {
+		return nil, nil //nolint:nilnil // Signing not requested
+	}
+
+	opts := &signatureOptions{
+		KeyFile: query.Get(paramSigKey),
+		KeyID:   query.Get(paramSigKeyID),
+	}
+
+	if opts.KeyFile != "" && !filepath.IsAbs(opts.KeyFile) {
+		return nil, fmt.Errorf("%s must be an absolute path", paramSigKey)
+	}
+
+	query.Del(paramSigKey)
+	query.Del(paramSigKeyID)
+	parsedURL.RawQuery = query.Encode()
+
+	return opts, nil
+}
+
+// sign adds an HTTP message signature to the request using the device key.
+func (o *signatureOptions) sign(req *http.Request) error {
+	keyFile := o.KeyFile
+	if keyFile == "" {
+		keyFile = httpsig.KeyFile(req.Context())
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read device key: %w", err)
+	}
+
+	key, err := httpsig.ParsePrivateKey(data)
+	if err != nil {
+		return fmt.Errorf("failed to load device key: %w", err)
+	}
+
+	err = httpsig.Sign(req, key, o.KeyID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	return nil
+}
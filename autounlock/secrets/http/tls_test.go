@@ -0,0 +1,350 @@
+package http
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Testing objectives:
+// - Verify that a custom CA bundle is trusted, and that an unrelated one is not.
+// - Verify SPKI and certificate fingerprint pinning, and refusal when no pin matches.
+// - Verify that a client certificate is presented for mutual TLS.
+// - Ensure that TLS parameters are stripped from the URL and invalid combinations rejected.
+
+// writePEM writes a PEM block to a file in dir and returns its path.
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+
+	return path
+}
+
+// newClientCert creates a self-signed CA and a client certificate issued by it.
+// Returns the CA pool and the paths to the client certificate and key files.
+func newClientCert(t *testing.T, dir string) (*x509.CertPool, string, string) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate client key: %v", err)
+	}
+
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "unraid"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	clientDER, err := x509.CreateCertificate(
+		rand.Reader,
+		clientTemplate,
+		caCert,
+		&clientKey.PublicKey,
+		caKey,
+	)
+	if err != nil {
+		t.Fatalf("failed to create client certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatalf("failed to marshal client key: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+
+	certPath := writePEM(t, dir, "client.pem", "CERTIFICATE", clientDER)
+	keyPath := writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+
+	return pool, certPath, keyPath
+}
+
+func newShareServer(body string) *httptest.Server {
+	return httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has(paramTLSPin) || r.URL.Query().Has(paramTLSCA) {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}))
+}
+
+// TestFetch_CustomCA tests that a server is trusted when its certificate is in the CA bundle.
+func TestFetch_CustomCA(t *testing.T) {
+	server := newShareServer("ca-content")
+	server.StartTLS()
+	defer server.Close()
+
+	caPath := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	fetcher := &Fetcher{}
+
+	got, err := fetcher.Fetch(context.Background(), server.URL+"/share?tls-ca="+caPath)
+	if err != nil {
+		t.Fatalf("Fetch with custom CA failed: %v", err)
+	}
+
+	if got != "ca-content" {
+		t.Errorf("Fetch() = %q, want %q", got, "ca-content")
+	}
+}
+
+// TestFetch_CustomCA_WrongCA tests that an unrelated CA bundle does not trust the server.
+func TestFetch_CustomCA_WrongCA(t *testing.T) {
+	server := newShareServer("ca-content")
+	server.StartTLS()
+	defer server.Close()
+
+	// A certificate from an unrelated CA must not be accepted as a trust anchor.
+	_, certPath, _ := newClientCert(t, t.TempDir())
+
+	fetcher := &Fetcher{}
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"?tls-ca="+certPath)
+	if err == nil {
+		t.Error("Expected certificate verification error, got none")
+	}
+}
+
+// TestFetch_PinSPKI tests SPKI pinning against a self-signed server.
+func TestFetch_PinSPKI(t *testing.T) {
+	server := newShareServer("pinned-content")
+	server.StartTLS()
+	defer server.Close()
+
+	sum := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	pinValue := url.QueryEscape("sha256/" + base64.StdEncoding.EncodeToString(sum[:]))
+
+	fetcher := &Fetcher{}
+
+	got, err := fetcher.Fetch(context.Background(), server.URL+"?tls-pin="+pinValue)
+	if err != nil {
+		t.Fatalf("Fetch with SPKI pin failed: %v", err)
+	}
+
+	if got != "pinned-content" {
+		t.Errorf("Fetch() = %q, want %q", got, "pinned-content")
+	}
+}
+
+// TestFetch_PinCertificateFingerprint tests certificate fingerprint pinning.
+func TestFetch_PinCertificateFingerprint(t *testing.T) {
+	server := newShareServer("fingerprint-content")
+	server.StartTLS()
+	defer server.Close()
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+
+	fetcher := &Fetcher{}
+
+	got, err := fetcher.Fetch(
+		context.Background(),
+		server.URL+"?tls-pin=sha256:"+strings.ToUpper(hex.EncodeToString(sum[:])),
+	)
+	if err != nil {
+		t.Fatalf("Fetch with fingerprint pin failed: %v", err)
+	}
+
+	if got != "fingerprint-content" {
+		t.Errorf("Fetch() = %q, want %q", got, "fingerprint-content")
+	}
+}
+
+// TestFetch_PinMismatch tests that a connection is refused when no pin matches.
+func TestFetch_PinMismatch(t *testing.T) {
+	server := newShareServer("pinned-content")
+	server.StartTLS()
+	defer server.Close()
+
+	wrong := strings.Repeat("00", sha256.Size)
+
+	fetcher := &Fetcher{}
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"?tls-pin=sha256:"+wrong)
+	if err == nil {
+		t.Fatal("Expected pin mismatch error, got none")
+	}
+
+	if !strings.Contains(err.Error(), "does not match any configured pin") {
+		t.Errorf("Expected pin mismatch error, got: %v", err)
+	}
+}
+
+// TestFetch_MutualTLS tests that a client certificate is presented to the server.
+func TestFetch_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCAs, certPath, keyPath := newClientCert(t, dir)
+
+	server := newShareServer("mtls-content")
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caPath := writePEM(t, dir, "server-ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	fetcher := &Fetcher{}
+
+	// Without a client certificate the handshake must fail.
+	_, err := fetcher.Fetch(context.Background(), server.URL+"?tls-ca="+caPath)
+	if err == nil {
+		t.Error("Expected handshake failure without client certificate, got none")
+	}
+
+	got, err := fetcher.Fetch(
+		context.Background(),
+		server.URL+"?tls-ca="+caPath+"&tls-cert="+certPath+"&tls-key="+keyPath,
+	)
+	if err != nil {
+		t.Fatalf("Fetch with client certificate failed: %v", err)
+	}
+
+	if got != "mtls-content" {
+		t.Errorf("Fetch() = %q, want %q", got, "mtls-content")
+	}
+}
+
+// TestParseTLSOptions_StripsReservedParams tests that TLS parameters are not sent to the server.
+func TestParseTLSOptions_StripsReservedParams(t *testing.T) {
+	parsedURL, insecure, err := parseURL(
+		"https://example.com/share?id=1&tls-ca=/ca.pem&tls-cert=/c.pem&tls-key=/c.key",
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	opts, err := parseTLSOptions(parsedURL, insecure)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if opts.CAFile != "/ca.pem" || opts.CertFile != "/c.pem" || opts.KeyFile != "/c.key" {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	if parsedURL.RawQuery != "id=1" {
+		t.Errorf("RawQuery = %q, want %q", parsedURL.RawQuery, "id=1")
+	}
+}
+
+// TestParseTLSOptions_Errors tests invalid TLS option combinations.
+func TestParseTLSOptions_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		url  string
+	}{
+		{"cert without key", "https://example.com/?tls-cert=/c.pem"},
+		{"key without cert", "https://example.com/?tls-key=/c.key"},
+		{"options on http", "http://example.com/?tls-ca=/ca.pem"},
+		{"insecure with pin", "https+insecure://example.com/?tls-pin=sha256:" + strings.Repeat("ab", 32)},
+		{"insecure with ca", "https+insecure://example.com/?tls-ca=/ca.pem"},
+		{"unknown pin format", "https://example.com/?tls-pin=md5:abcd"},
+		{"short fingerprint", "https://example.com/?tls-pin=sha256:abcd"},
+		{"invalid spki", "https://example.com/?tls-pin=sha256/not-base64!"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parsedURL, insecure, err := parseURL(tc.url)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %v", err)
+			}
+
+			_, err = parseTLSOptions(parsedURL, insecure)
+			if err == nil {
+				t.Errorf("Expected error for %q, got none", tc.url)
+			}
+		})
+	}
+}
+
+// TestParsePin_UnescapedPlus tests that a '+' decoded as a space is restored.
+func TestParsePin_UnescapedPlus(t *testing.T) {
+	// 0xfbefbe encodes to "++++" in base64.
+	hash := bytes.Repeat([]byte{0xfb, 0xef, 0xbe}, 11)[:sha256.Size]
+	encoded := base64.StdEncoding.EncodeToString(hash)
+
+	parsed, err := parsePin("sha256/" + strings.ReplaceAll(encoded, "+", " "))
+	if err != nil {
+		t.Fatalf("parsePin failed: %v", err)
+	}
+
+	if !parsed.spki || !bytes.Equal(parsed.hash, hash) {
+		t.Errorf("Unexpected pin: %+v", parsed)
+	}
+}
@@ -0,0 +1,272 @@
+package ldap
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/registry"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/tlsconfig"
+)
+
+const (
+	// PriorityLDAP is the priority for the LDAP fetcher (explicit prefix).
+	PriorityLDAP = 26
+
+	paramAttr         = "attr"
+	paramBindDN       = "bind-dn"
+	paramBindPassword = "bind-password"
+	paramStartTLS     = "starttls"
+
+	defaultPort       = "389"
+	defaultSecurePort = "636"
+)
+
+func init() {
+	registry.Register(&Fetcher{})
+}
+
+type Fetcher struct{}
+
+func (f *Fetcher) Match(path string) bool {
+	return strings.HasPrefix(path, "ldap://") || strings.HasPrefix(path, "ldaps://")
+}
+
+func (f *Fetcher) Priority() int {
+	return PriorityLDAP
+}
+
+func (f *Fetcher) Name() string {
+	return "ldap"
+}
+
+// Validate checks the location without connecting to it.
+func (f *Fetcher) Validate(path string) error {
+	_, err := parseOptions(path)
+
+	return err
+}
+
+// ApplyParams adds params from the configuration file to the URL query string.
+func (f *Fetcher) ApplyParams(path string, params map[string]string) (string, error) {
+	return registry.MergeQuery(path, params) //nolint:wrapcheck // Errors are already descriptive
+}
+
+type options struct {
+	address      string
+	entryDN      string
+	attribute    string
+	bindDN       string
+	bindPassword string
+	secure       bool
+	startTLS     bool
+	tlsConfig    *tls.Config
+}
+
+// Fetch binds to the directory and reads a single-valued attribute from an entry.
+// Supported formats:
+//   - ldap://host/cn=unraid,ou=shares,dc=example,dc=com?attr=description (anonymous)
+//   - ldap://host/DN?attr=ATTR&bind-dn=BINDDN&bind-password=PASS&starttls=true
+//   - ldaps://host:636/DN?attr=ATTR&bind-dn=BINDDN&bind-password=PASS&tls-ca=/path/ca.pem
+func (f *Fetcher) Fetch(ctx context.Context, path string) (string, error) {
+	opts, err := parseOptions(path)
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := dial(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// go-ldap does not take a context; closing the connection aborts any pending request.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetTimeout(time.Until(deadline))
+	}
+
+	if opts.startTLS {
+		err = conn.StartTLS(opts.tlsConfig)
+		if err != nil {
+			return "", fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if opts.bindDN != "" {
+		err = conn.Bind(opts.bindDN, opts.bindPassword)
+		if err != nil {
+			return "", fmt.Errorf("failed to bind as %s: %w", opts.bindDN, err)
+		}
+	}
+
+	return readAttribute(conn, opts)
+}
+
+func dial(ctx context.Context, opts options) (*goldap.Conn, error) {
+	dialer := &net.Dialer{}
+
+	var (
+		netConn net.Conn
+		err     error
+	)
+
+	if opts.secure {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: opts.tlsConfig}
+		netConn, err = tlsDialer.DialContext(ctx, "tcp", opts.address)
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", opts.address)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", opts.address, err)
+	}
+
+	conn := goldap.NewConn(netConn, opts.secure)
+	conn.Start()
+
+	return conn, nil
+}
+
+func readAttribute(conn *goldap.Conn, opts options) (string, error) {
+	request := goldap.NewSearchRequest(
+		opts.entryDN,
+		goldap.ScopeBaseObject,
+		goldap.NeverDerefAliases,
+		1,
+		0,
+		false,
+		"(objectClass=*)",
+		[]string{opts.attribute},
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+		return "", fmt.Errorf("entry not found: %s", opts.entryDN)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", opts.entryDN, err)
+	}
+
+	if len(result.Entries) != 1 {
+		return "", fmt.Errorf("entry not found: %s", opts.entryDN)
+	}
+
+	values := result.Entries[0].GetEqualFoldAttributeValues(opts.attribute)
+
+	switch {
+	case len(values) == 0:
+		return "", fmt.Errorf("attribute %s not found on %s", opts.attribute, opts.entryDN)
+	case len(values) > 1:
+		return "", fmt.Errorf("attribute %s has %d values, expected one", opts.attribute, len(values))
+	}
+
+	share := strings.TrimSpace(values[0])
+	if share == "" {
+		return "", fmt.Errorf("attribute %s is empty", opts.attribute)
+	}
+
+	return share, nil
+}
+
+func parseOptions(path string) (options, error) {
+	parsedURL, err := url.Parse(path)
+	if err != nil {
+		return options{}, fmt.Errorf("invalid LDAP URL: %w", err)
+	}
+
+	if parsedURL.Hostname() == "" {
+		return options{}, errors.New("LDAP URL must include a host")
+	}
+
+	if parsedURL.User != nil {
+		return options{}, errors.New("LDAP credentials must be set with bind-dn and bind-password")
+	}
+
+	query := parsedURL.Query()
+
+	opts := options{
+		entryDN:      strings.TrimPrefix(parsedURL.Path, "/"),
+		attribute:    query.Get(paramAttr),
+		bindDN:       query.Get(paramBindDN),
+		bindPassword: query.Get(paramBindPassword),
+		secure:       parsedURL.Scheme == "ldaps",
+	}
+
+	if opts.entryDN == "" {
+		return options{}, errors.New("LDAP URL must include the entry DN as the path")
+	}
+
+	if opts.attribute == "" {
+		return options{}, errors.New("LDAP URL must include an attr parameter")
+	}
+
+	// An empty password performs an unauthenticated bind, which succeeds without checking anything.
+	if opts.bindDN != "" && opts.bindPassword == "" {
+		return options{}, errors.New("bind-dn requires bind-password")
+	}
+
+	if query.Has(paramStartTLS) {
+		opts.startTLS, err = strconv.ParseBool(query.Get(paramStartTLS))
+		if err != nil {
+			return options{}, fmt.Errorf("invalid starttls value: %w", err)
+		}
+	}
+
+	if opts.startTLS && opts.secure {
+		return options{}, errors.New("starttls cannot be used with ldaps://")
+	}
+
+	if tlsconfig.Has(query) && !opts.secure && !opts.startTLS {
+		return options{}, errors.New("tls-* parameters require ldaps:// or starttls=true")
+	}
+
+	port := parsedURL.Port()
+	if port == "" {
+		port = defaultPort
+		if opts.secure {
+			port = defaultSecurePort
+		}
+	}
+
+	opts.address = net.JoinHostPort(parsedURL.Hostname(), port)
+
+	if opts.secure || opts.startTLS {
+		opts.tlsConfig, err = tlsconfig.Build(parsedURL.Hostname(), query)
+		if err != nil {
+			return options{}, err
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,189 @@
+package local
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// waitDelay bounds how long a helper's children may hold stdout open after it exits.
+	waitDelay = time.Second
+)
+
+// ExecFetcher runs a local executable and reads the share from its stdout.
+// Arguments are split on whitespace; no shell is involved.
+type ExecFetcher struct{}
+
+func (f *ExecFetcher) Match(path string) bool {
+	return strings.HasPrefix(path, "exec:")
+}
+
+func (f *ExecFetcher) Priority() int {
+	return PriorityLocal
+}
+
+func (f *ExecFetcher) Name() string {
+	return "exec"
+}
+
+// Validate checks the executable without running it.
+func (f *ExecFetcher) Validate(path string) error {
+	_, err := parseCommand(path)
+
+	return err
+}
+
+// Fetch runs the executable and returns its trimmed output.
+// Supported formats:
+//   - exec:/usr/local/bin/helper
+//   - exec:/usr/local/bin/helper --share box1
+func (f *ExecFetcher) Fetch(ctx context.Context, path string) (string, error) {
+	fields, err := parseCommand(path)
+	if err != nil {
+		return "", err
+	}
+
+	executable := fields[0]
+
+	var stdout, stderr bytes.Buffer
+
+	//nolint:gosec // Running the configured helper is the purpose of this fetcher
+	cmd := exec.CommandContext(ctx, executable, fields[1:]...)
+	cmd.Stdout = &limitedBuffer{buf: &stdout, limit: maxShareSize}
+	cmd.Stderr = &limitedBuffer{buf: &stderr, limit: maxShareSize}
+	cmd.WaitDelay = waitDelay
+
+	err = cmd.Run()
+	if stderr.Len() > 0 {
+		log.Debug().Str("executable", executable).Str("stderr", stderr.String()).Msg("Helper output")
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("helper %s failed: %w", executable, err)
+	}
+
+	share := strings.TrimSpace(stdout.String())
+	if share == "" {
+		return "", fmt.Errorf("helper %s returned an empty share", executable)
+	}
+
+	return share, nil
+}
+
+// parseCommand splits the location into the executable and its arguments, checking
+// that the executable is safe to run.
+func parseCommand(path string) ([]string, error) {
+	fields := strings.Fields(strings.TrimPrefix(path, "exec:"))
+	if len(fields) == 0 {
+		return nil, errors.New("exec location must name an executable: exec:/path/to/helper")
+	}
+
+	err := checkExecutable(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// checkExecutable requires an absolute path to a regular file that only root can modify,
+// since the helper runs as root and its output unlocks the array: the file and the
+// directory holding it must be owned by root (or the user running autounlock) and not
+// writable by group or others.
+func checkExecutable(executable string) error {
+	if !filepath.IsAbs(executable) {
+		return fmt.Errorf("executable must be an absolute path: %s", executable)
+	}
+
+	resolved, err := filepath.EvalSymlinks(executable)
+	if err != nil {
+		return fmt.Errorf("failed to resolve executable: %w", err)
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return fmt.Errorf("failed to stat executable: %w", err)
+	}
+
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("executable is not a regular file: %s", executable)
+	}
+
+	err = checkOwnership(info, "executable", executable)
+	if err != nil {
+		return err
+	}
+
+	dirInfo, err := os.Stat(filepath.Dir(resolved))
+	if err != nil {
+		return fmt.Errorf("failed to stat executable directory: %w", err)
+	}
+
+	return checkOwnership(dirInfo, "executable directory", filepath.Dir(resolved))
+}
+
+// checkOwnership rejects a file or directory that a user other than root, or the one
+// running autounlock, could modify.
+func checkOwnership(info os.FileInfo, what string, path string) error {
+	if info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("%s must not be group or world writable: %s", what, path)
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("failed to read the owner of %s: %s", what, path)
+	}
+
+	if stat.Uid != 0 && int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("%s must be owned by root: %s", what, path)
+	}
+
+	return nil
+}
+
+// limitedBuffer discards output beyond limit so a misbehaving helper cannot exhaust memory.
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if remaining > 0 {
+		if len(data) > remaining {
+			b.buf.Write(data[:remaining])
+		} else {
+			b.buf.Write(data)
+		}
+	}
+
+	return len(data), nil
+}
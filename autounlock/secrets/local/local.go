@@ -0,0 +1,35 @@
+package local
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/registry"
+)
+
+const (
+	// PriorityLocal is the priority for local helper fetchers (explicit prefixes).
+	PriorityLocal = 12
+
+	maxShareSize = 64 * 1024
+)
+
+func init() {
+	registry.Register(&UnixFetcher{})
+	registry.Register(&ExecFetcher{})
+}
@@ -0,0 +1,283 @@
+package local
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Testing objectives:
+// - Verify that the Unix socket fetcher speaks the line protocol and handles errors.
+// - Verify that the exec fetcher returns helper output and reports failures.
+// - Ensure that both fetchers stop when the context expires.
+// - Ensure that unsafe executables are refused, including ones owned by other users or
+//   in a directory that others can write to.
+
+// serveSocket starts a helper on a Unix socket that answers each request with respond.
+func serveSocket(t *testing.T, respond func(request string) string) string {
+	t.Helper()
+
+	// Socket paths are limited to ~100 bytes, so avoid the long t.TempDir() path.
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatalf("failed to create socket directory: %v", err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "helper.sock")
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				request, _ := bufio.NewReader(conn).ReadString('\n')
+
+				response := respond(strings.TrimSuffix(request, "\n"))
+				if response != "" {
+					conn.Write([]byte(response))
+				}
+			}()
+		}
+	}()
+
+	return path
+}
+
+// TestUnixFetcher_Protocol tests requests with and without a share name.
+func TestUnixFetcher_Protocol(t *testing.T) {
+	socket := serveSocket(t, func(request string) string {
+		switch request {
+		case "GET":
+			return "OK default-share\n"
+		case "GET box1":
+			return "OK box1-share\n"
+		default:
+			return "ERR unknown share\n"
+		}
+	})
+
+	testCases := []struct {
+		location string
+		want     string
+		wantErr  bool
+	}{
+		{"unix://" + socket, "default-share", false},
+		{"unix://" + socket + "#box1", "box1-share", false},
+		{"unix://" + socket + "#box2", "", true},
+	}
+
+	for _, tc := range testCases {
+		got, err := (&UnixFetcher{}).Fetch(context.Background(), tc.location)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tc.location, err, tc.wantErr)
+		}
+
+		if got != tc.want {
+			t.Errorf("%s: share = %q, want %q", tc.location, got, tc.want)
+		}
+	}
+}
+
+// TestUnixFetcher_InvalidResponses tests malformed and empty responses.
+func TestUnixFetcher_InvalidResponses(t *testing.T) {
+	for _, response := range []string{"HELLO\n", "OK \n", ""} {
+		socket := serveSocket(t, func(string) string { return response })
+
+		_, err := (&UnixFetcher{}).Fetch(context.Background(), "unix://"+socket)
+		if err == nil {
+			t.Errorf("expected error for response %q, got none", response)
+		}
+	}
+}
+
+// TestUnixFetcher_Timeout tests that a helper which never answers does not block past the deadline.
+func TestUnixFetcher_Timeout(t *testing.T) {
+	socket := serveSocket(t, func(string) string {
+		time.Sleep(2 * time.Second)
+
+		return ""
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := (&UnixFetcher{}).Fetch(ctx, "unix://"+socket)
+	if err == nil {
+		t.Error("expected timeout error, got none")
+	}
+
+	if time.Since(start) > time.Second {
+		t.Errorf("Fetch took %v, expected to stop at the deadline", time.Since(start))
+	}
+}
+
+// TestUnixFetcher_InvalidLocation tests locations that are not absolute socket paths.
+func TestUnixFetcher_InvalidLocation(t *testing.T) {
+	for _, location := range []string{"unix://run/helper.sock", "unix://", "unix:///run/helper.sock#two words"} {
+		_, err := (&UnixFetcher{}).Fetch(context.Background(), location)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", location)
+		}
+	}
+}
+
+// writableDirScript writes a safe script into a directory that anyone can write to.
+func writableDirScript(t *testing.T) string {
+	t.Helper()
+
+	script := writeScript(t, "echo share", 0o700)
+
+	err := os.Chmod(filepath.Dir(script), 0o777)
+	if err != nil {
+		t.Fatalf("failed to chmod directory: %v", err)
+	}
+
+	return script
+}
+
+// writeScript writes an executable shell script and returns its path.
+func writeScript(t *testing.T, body string, mode os.FileMode) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "helper.sh")
+
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), mode)
+	if err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	err = os.Chmod(path, mode)
+	if err != nil {
+		t.Fatalf("failed to chmod script: %v", err)
+	}
+
+	return path
+}
+
+// TestExecFetcher_Output tests that stdout is returned and arguments are passed through.
+func TestExecFetcher_Output(t *testing.T) {
+	script := writeScript(t, `echo "share-$1"; echo "diagnostics" >&2`, 0o700)
+
+	got, err := (&ExecFetcher{}).Fetch(context.Background(), "exec:"+script+" box1")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if got != "share-box1" {
+		t.Errorf("share = %q, want %q", got, "share-box1")
+	}
+}
+
+// TestExecFetcher_Failures tests non-zero exits, empty output and unsafe executables.
+func TestExecFetcher_Failures(t *testing.T) {
+	testCases := map[string]string{
+		"non-zero exit":  "exec:" + writeScript(t, "echo share; exit 3", 0o700),
+		"empty output":   "exec:" + writeScript(t, "true", 0o700),
+		"world writable": "exec:" + writeScript(t, "echo share", 0o777),
+		"writable dir":   "exec:" + writableDirScript(t),
+		"relative path":  "exec:helper.sh",
+		"missing":        "exec:/nonexistent/helper",
+		"no executable":  "exec:",
+		"directory":      "exec:" + t.TempDir(),
+	}
+
+	for name, location := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := (&ExecFetcher{}).Fetch(context.Background(), location)
+			if err == nil {
+				t.Errorf("expected error for %s, got none", location)
+			}
+		})
+	}
+}
+
+// TestExecFetcher_Owner tests that executables and directories owned by others are refused.
+func TestExecFetcher_Owner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+
+	const nobody = 65534
+
+	script := writeScript(t, "echo share", 0o755)
+
+	err := os.Chown(script, nobody, nobody)
+	if err != nil {
+		t.Fatalf("failed to chown script: %v", err)
+	}
+
+	_, err = (&ExecFetcher{}).Fetch(context.Background(), "exec:"+script)
+	if err == nil || !strings.Contains(err.Error(), "owned by root") {
+		t.Errorf("script owned by another user: err = %v, want refused", err)
+	}
+
+	script = writeScript(t, "echo share", 0o755)
+
+	err = os.Chown(filepath.Dir(script), nobody, nobody)
+	if err != nil {
+		t.Fatalf("failed to chown directory: %v", err)
+	}
+
+	_, err = (&ExecFetcher{}).Fetch(context.Background(), "exec:"+script)
+	if err == nil || !strings.Contains(err.Error(), "executable directory") {
+		t.Errorf("directory owned by another user: err = %v, want refused", err)
+	}
+}
+
+// TestExecFetcher_Timeout tests that a hanging helper is killed at the deadline.
+func TestExecFetcher_Timeout(t *testing.T) {
+	script := writeScript(t, "sleep 10; echo share", 0o700)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := (&ExecFetcher{}).Fetch(ctx, "exec:"+script)
+	if err == nil {
+		t.Error("expected timeout error, got none")
+	}
+
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("Fetch took %v, expected the helper to be killed", time.Since(start))
+	}
+}
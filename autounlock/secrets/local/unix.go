@@ -0,0 +1,145 @@
+package local
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// UnixFetcher reads shares from a helper daemon listening on a Unix socket.
+//
+// The protocol is a single request and response line:
+//
+//	GET <name>\n      (name is empty if the location has no #fragment)
+//	OK <share>\n      or      ERR <message>\n
+type UnixFetcher struct{}
+
+func (f *UnixFetcher) Match(path string) bool {
+	return strings.HasPrefix(path, "unix://")
+}
+
+func (f *UnixFetcher) Priority() int {
+	return PriorityLocal
+}
+
+func (f *UnixFetcher) Name() string {
+	return "unix"
+}
+
+// Validate checks the location without connecting to the socket.
+func (f *UnixFetcher) Validate(path string) error {
+	_, _, err := parseSocket(path)
+
+	return err
+}
+
+// parseSocket returns the socket URL and the name of the share to request.
+func parseSocket(path string) (*url.URL, string, error) {
+	parsed, err := url.Parse(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid unix socket location: %w", err)
+	}
+
+	if parsed.Host != "" || parsed.Path == "" {
+		return nil, "", errors.New(
+			"unix socket location must be an absolute path: unix:///path/to/socket",
+		)
+	}
+
+	name := parsed.Fragment
+	if strings.ContainsAny(name, " \r\n") {
+		return nil, "", fmt.Errorf("invalid share name: %q", name)
+	}
+
+	return parsed, name, nil
+}
+
+// Fetch requests a share from a Unix socket.
+// Supported formats:
+//   - unix:///run/helper.sock
+//   - unix:///run/helper.sock#NAME (ask the helper for a named share)
+func (f *UnixFetcher) Fetch(ctx context.Context, path string) (string, error) {
+	parsed, name, err := parseSocket(path)
+	if err != nil {
+		return "", err
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "unix", parsed.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", parsed.Path, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return "", fmt.Errorf("failed to set socket deadline: %w", err)
+		}
+	}
+
+	// Unblock reads if the context is cancelled without a deadline.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	request := "GET"
+	if name != "" {
+		request += " " + name
+	}
+
+	_, err = io.WriteString(conn, request+"\n")
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+
+	return readResponse(bufio.NewReaderSize(io.LimitReader(conn, maxShareSize), maxShareSize))
+}
+
+func readResponse(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+
+	status, payload, _ := strings.Cut(line, " ")
+
+	switch status {
+	case "OK":
+		share := strings.TrimSpace(payload)
+		if share == "" {
+			return "", errors.New("helper returned an empty share")
+		}
+
+		return share, nil
+	case "ERR":
+		return "", fmt.Errorf("helper returned error: %s", payload)
+	default:
+		return "", fmt.Errorf("invalid helper response: %q", status)
+	}
+}
@@ -0,0 +1,233 @@
+package mdns
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	hcmdns "github.com/hashicorp/mdns"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/http"
+)
+
+const (
+	// ServiceType is the service advertised by the serve subcommand.
+	ServiceType = "_autounlock._tcp"
+
+	domain        = "local"
+	browseTimeout = 2 * time.Second
+	probeTimeout  = 5 * time.Second
+	maxEntries    = 32
+)
+
+// Resolver discovers peers advertising a service type.
+type Resolver interface {
+	Browse(ctx context.Context, service string) ([]Peer, error)
+}
+
+type multicastResolver struct {
+	wait time.Duration
+}
+
+// Browse sends an mDNS query and collects the answers received within the wait time
+// (browseTimeout by default), or until ctx expires if sooner.
+func (r multicastResolver) Browse(ctx context.Context, service string) ([]Peer, error) {
+	timeout := r.wait
+	if timeout == 0 {
+		timeout = browseTimeout
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	entries := make(chan *hcmdns.ServiceEntry, maxEntries)
+	suffix := "." + service + "." + domain + "."
+
+	var peers []Peer
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for entry := range entries {
+			peer, ok := toPeer(entry, suffix)
+			if ok {
+				peers = append(peers, peer)
+			}
+		}
+	}()
+
+	err := hcmdns.QueryContext(ctx, &hcmdns.QueryParam{
+		Service: service,
+		Domain:  domain,
+		Timeout: timeout,
+		Entries: entries,
+		Logger:  stdlog.New(io.Discard, "", 0),
+	})
+
+	close(entries)
+	<-done
+
+	if err != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("mDNS query failed: %w", err)
+	}
+
+	slices.SortFunc(peers, func(a, b Peer) int { return strings.Compare(a.Instance, b.Instance) })
+
+	return peers, nil
+}
+
+func toPeer(entry *hcmdns.ServiceEntry, suffix string) (Peer, bool) {
+	if !strings.HasSuffix(entry.Name, suffix) {
+		return Peer{}, false
+	}
+
+	instance := strings.ReplaceAll(strings.TrimSuffix(entry.Name, suffix), `\`, "")
+
+	switch {
+	case entry.AddrV4 != nil:
+		return Peer{Instance: instance, IP: entry.AddrV4, Port: entry.Port}, true
+	case entry.AddrV6IPAddr != nil && !entry.AddrV6IPAddr.IP.IsLinkLocalUnicast():
+		// Link-local addresses would need a zone, which cannot be expressed in the URL host.
+		return Peer{Instance: instance, IP: entry.AddrV6IPAddr.IP, Port: entry.Port}, true
+	default:
+		return Peer{}, false
+	}
+}
+
+// Advertise announces a share server on the LAN until the returned closer is closed.
+func Advertise(instance string, port int) (io.Closer, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	if instance == "" {
+		instance = host
+	}
+
+	service, err := hcmdns.NewMDNSService(
+		instance, ServiceType, domain, host+".", port, nil, []string{"txtvers=1"},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mDNS service: %w", err)
+	}
+
+	server, err := hcmdns.NewServer(&hcmdns.Config{Zone: service, Logger: stdlog.New(io.Discard, "", 0)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start mDNS responder: %w", err)
+	}
+
+	return shutdownCloser{server}, nil
+}
+
+type shutdownCloser struct {
+	server *hcmdns.Server
+}
+
+func (c shutdownCloser) Close() error {
+	return c.server.Shutdown() //nolint:wrapcheck // Pass-through closer
+}
+
+// Discovered is a peer found by Discover, with the pin of the key it presented.
+type Discovered struct {
+	Peer
+	Pin      string
+	Location string
+	Err      error
+}
+
+// Discover browses for peers for the wait time and records the key each one presents,
+// so that the printed locations pin the peer seen at setup.
+func Discover(
+	ctx context.Context,
+	service string,
+	wait time.Duration,
+	resolver Resolver,
+) ([]Discovered, error) {
+	if resolver == nil {
+		resolver = multicastResolver{wait: wait}
+	}
+
+	peers, err := resolver.Browse(ctx, service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %w", service, err)
+	}
+
+	found := make([]Discovered, 0, len(peers))
+
+	for _, peer := range peers {
+		result := Discovered{Peer: peer}
+
+		result.Pin, result.Err = probePin(ctx, peer)
+		if result.Err == nil {
+			result.Location = fmt.Sprintf(
+				"%s%s.%s/SHARE?%s=%s",
+				prefix, peer.Instance, service, paramTLSPin, result.Pin,
+			)
+		}
+
+		found = append(found, result)
+	}
+
+	return found, nil
+}
+
+// probePin connects to the peer and returns the pin of its certificate's public key.
+// The certificate is not verified; the caller decides whether to trust it.
+func probePin(ctx context.Context, peer Peer) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			InsecureSkipVerify: true, //nolint:gosec // Only the presented key is recorded
+			MinVersion:         tls.VersionTLS12,
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", peer.Address())
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", peer.Address(), err)
+	}
+	defer conn.Close()
+
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return "", errors.New("unexpected connection type")
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", fmt.Errorf("%s did not present a certificate", peer.Address())
+	}
+
+	return http.SPKIPin(certs[0]), nil
+}
@@ -0,0 +1,218 @@
+package mdns
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/http"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/registry"
+)
+
+const (
+	// PriorityMDNS is the priority for the mDNS fetcher (explicit prefix).
+	PriorityMDNS = 21
+
+	paramTLSPin = "tls-pin"
+	prefix      = "mdns:"
+)
+
+func init() {
+	registry.Register(&Fetcher{})
+}
+
+type Fetcher struct {
+	// Resolver can be optionally set for testing. If nil, multicast DNS is used.
+	Resolver Resolver
+	// HTTP can be optionally set for testing. If nil, the default HTTP fetcher is used.
+	HTTP registry.Fetcher
+}
+
+func (f *Fetcher) Match(path string) bool {
+	return strings.HasPrefix(path, prefix)
+}
+
+func (f *Fetcher) Priority() int {
+	return PriorityMDNS
+}
+
+func (f *Fetcher) Name() string {
+	return "mdns"
+}
+
+// Validate checks the location without connecting to it.
+func (f *Fetcher) Validate(path string) error {
+	_, err := parseLocation(path)
+
+	return err
+}
+
+// ApplyParams adds params from the configuration file to the URL query string.
+func (f *Fetcher) ApplyParams(path string, params map[string]string) (string, error) {
+	return registry.MergeQuery(path, params) //nolint:wrapcheck // Errors are already descriptive
+}
+
+type location struct {
+	userinfo string
+	instance string
+	service  string
+	rest     string
+}
+
+// Fetch discovers peers advertising the service on the LAN and fetches the share from
+// each in turn over HTTPS until one succeeds. The tls-pin parameter is required so that
+// only the peer whose key was recorded at setup is trusted.
+// Supported formats:
+//   - mdns:_autounlock._tcp/SHARE?tls-pin=sha256/BASE64 (any peer holding the pinned key)
+//   - mdns:Tower._autounlock._tcp/SHARE?tls-pin=sha256/BASE64 (a single named peer)
+//   - mdns:unraid:TOKEN@Tower._autounlock._tcp/SHARE?tls-pin=sha256/BASE64&sig-key=/path/device.key
+//
+// All query parameters are passed to the HTTP fetcher.
+func (f *Fetcher) Fetch(ctx context.Context, path string) (string, error) {
+	loc, err := parseLocation(path)
+	if err != nil {
+		return "", err
+	}
+
+	resolver := f.Resolver
+	if resolver == nil {
+		resolver = multicastResolver{}
+	}
+
+	fetcher := f.HTTP
+	if fetcher == nil {
+		fetcher = &http.Fetcher{}
+	}
+
+	peers, err := resolver.Browse(ctx, loc.service)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover %s: %w", loc.service, err)
+	}
+
+	var errs []error
+
+	for _, peer := range peers {
+		if loc.instance != "" && !strings.EqualFold(peer.Instance, loc.instance) {
+			continue
+		}
+
+		share, err := fetcher.Fetch(ctx, loc.target(peer))
+		if err == nil {
+			log.Debug().
+				Str("peer", peer.Instance).
+				Str("address", peer.Address()).
+				Msg("Fetched share from peer")
+
+			return share, nil
+		}
+
+		log.Debug().
+			Err(err).
+			Str("peer", peer.Instance).
+			Str("address", peer.Address()).
+			Msg("Peer did not serve share")
+
+		errs = append(errs, fmt.Errorf("%s: %w", peer.Instance, err))
+	}
+
+	if len(errs) == 0 {
+		return "", fmt.Errorf("no peers found for %s", loc.service)
+	}
+
+	return "", fmt.Errorf("no peer served the share: %w", errors.Join(errs...))
+}
+
+// target builds the https:// location for a discovered peer.
+func (l location) target(peer Peer) string {
+	target := "https://"
+	if l.userinfo != "" {
+		target += l.userinfo + "@"
+	}
+
+	return target + peer.Address() + "/" + l.rest
+}
+
+func parseLocation(path string) (location, error) {
+	spec := strings.TrimPrefix(path, prefix)
+
+	name, rest, found := strings.Cut(spec, "/")
+	if !found || rest == "" {
+		return location{}, errors.New("mDNS location must include a share path after the service")
+	}
+
+	var loc location
+
+	if at := strings.LastIndex(name, "@"); at >= 0 {
+		loc.userinfo, name = name[:at], name[at+1:]
+	}
+
+	loc.instance, loc.service = splitServiceName(name)
+	if loc.service == "" {
+		return location{}, fmt.Errorf("invalid mDNS service: %s", name)
+	}
+
+	loc.rest = rest
+
+	// Parse the remainder as the HTTP fetcher will, to validate it early.
+	parsedURL, err := url.Parse("https://peer/" + rest)
+	if err != nil {
+		return location{}, fmt.Errorf("invalid mDNS location: %w", err)
+	}
+
+	if !parsedURL.Query().Has(paramTLSPin) {
+		return location{}, errors.New("mDNS locations require tls-pin to identify the peer")
+	}
+
+	return loc, nil
+}
+
+// splitServiceName splits [INSTANCE.]_service._tcp into the instance and service type.
+func splitServiceName(name string) (string, string) {
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 { //nolint:mnd // Service type and protocol labels
+		return "", ""
+	}
+
+	service := labels[len(labels)-2:]
+	if !strings.HasPrefix(service[0], "_") || (service[1] != "_tcp" && service[1] != "_udp") {
+		return "", ""
+	}
+
+	return strings.Join(labels[:len(labels)-2], "."), strings.Join(service, ".")
+}
+
+// Peer is a share server discovered on the LAN.
+type Peer struct {
+	Instance string
+	IP       net.IP
+	Port     int
+}
+
+// Address returns the host:port of the peer.
+func (p Peer) Address() string {
+	return net.JoinHostPort(p.IP.String(), strconv.Itoa(p.Port))
+}
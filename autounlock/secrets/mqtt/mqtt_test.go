@@ -0,0 +1,337 @@
+package mqtt
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+// Testing objectives:
+// - Verify that the retained message on a topic is returned.
+// - Verify username/password and client certificate authentication against an embedded broker.
+// - Ensure that a topic without a retained message fails at the context deadline.
+// - Ensure that invalid MQTT URLs are rejected.
+
+const (
+	testUser     = "unraid"
+	testPassword = "hunter2"
+)
+
+// freeAddress returns a loopback address with an unused port.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find free port: %v", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+// startBroker starts an embedded broker requiring testUser/testPassword and publishes
+// the retained messages.
+func startBroker(t *testing.T, tlsConfig *tls.Config, retained map[string]string) string {
+	t.Helper()
+
+	broker := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	rules := auth.AuthRules{{Username: testUser, Password: testPassword, Allow: true}}
+	if tlsConfig != nil {
+		// Clients authenticate with certificates on the TLS listener.
+		rules = auth.AuthRules{{Allow: true}}
+	}
+
+	err := broker.AddHook(new(auth.Hook), &auth.Options{Ledger: &auth.Ledger{Auth: rules}})
+	if err != nil {
+		t.Fatalf("failed to add auth hook: %v", err)
+	}
+
+	address := freeAddress(t)
+
+	err = broker.AddListener(listeners.NewTCP(listeners.Config{
+		ID:        "test",
+		Address:   address,
+		TLSConfig: tlsConfig,
+	}))
+	if err != nil {
+		t.Fatalf("failed to add listener: %v", err)
+	}
+
+	err = broker.Serve()
+	if err != nil {
+		t.Fatalf("failed to start broker: %v", err)
+	}
+
+	t.Cleanup(func() { broker.Close() })
+
+	for topic, payload := range retained {
+		err = broker.Publish(topic, []byte(payload), true, 1)
+		if err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+	}
+
+	return address
+}
+
+func fetch(t *testing.T, location string, timeout time.Duration) (string, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return (&Fetcher{}).Fetch(ctx, location)
+}
+
+// TestFetch_RetainedMessage tests fetching a retained message with username and password.
+func TestFetch_RetainedMessage(t *testing.T) {
+	address := startBroker(t, nil, map[string]string{"home/unraid/share": "mqtt-share\n"})
+
+	got, err := fetch(t, "mqtt://"+testUser+":"+testPassword+"@"+address+"/home/unraid/share", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if got != "mqtt-share" {
+		t.Errorf("share = %q, want %q", got, "mqtt-share")
+	}
+}
+
+// TestFetch_WrongPassword tests that the broker rejecting credentials is reported.
+func TestFetch_WrongPassword(t *testing.T) {
+	address := startBroker(t, nil, map[string]string{"home/unraid/share": "mqtt-share"})
+
+	_, err := fetch(t, "mqtt://"+testUser+":wrong@"+address+"/home/unraid/share", 5*time.Second)
+	if err == nil {
+		t.Error("expected error for wrong password, got none")
+	}
+}
+
+// TestFetch_NoRetainedMessage tests that waiting stops at the context deadline.
+func TestFetch_NoRetainedMessage(t *testing.T) {
+	address := startBroker(t, nil, nil)
+
+	start := time.Now()
+
+	_, err := fetch(t, "mqtt://"+testUser+":"+testPassword+"@"+address+"/home/unraid/none", 300*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "no retained message") {
+		t.Errorf("expected no retained message error, got %v", err)
+	}
+
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("Fetch took %v, expected to stop at the deadline", time.Since(start))
+	}
+}
+
+// TestFetch_ClientCertificate tests mqtts:// with a custom CA and client certificate.
+func TestFetch_ClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	pki := newPKI(t, dir)
+
+	address := startBroker(t, pki.serverTLS, map[string]string{"unraid/share": "tls-share"})
+
+	base := "mqtts://" + address + "/unraid/share?tls-ca=" + pki.caFile
+
+	got, err := fetch(t, base+"&tls-cert="+pki.clientCert+"&tls-key="+pki.clientKey, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if got != "tls-share" {
+		t.Errorf("share = %q, want %q", got, "tls-share")
+	}
+
+	_, err = fetch(t, base, 2*time.Second)
+	if err == nil {
+		t.Error("expected error without client certificate, got none")
+	}
+}
+
+// TestParseOptions tests URL parsing defaults and validation.
+func TestParseOptions(t *testing.T) {
+	opts, err := parseOptions("mqtts://u:p@broker.local/a/b%20c?client-id=tower")
+	if err != nil {
+		t.Fatalf("parseOptions failed: %v", err)
+	}
+
+	if opts.Broker != "ssl://broker.local:8883" || opts.Topic != "a/b c" || opts.ClientID != "tower" ||
+		opts.Username != "u" || opts.Password != "p" || opts.TLS == nil {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	opts, err = parseOptions("mqtt://broker.local/topic")
+	if err != nil {
+		t.Fatalf("parseOptions failed: %v", err)
+	}
+
+	if opts.Broker != "tcp://broker.local:1883" || !strings.HasPrefix(opts.ClientID, "autounlock-") {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	for _, invalid := range []string{
+		"mqtt:///topic",
+		"mqtt://broker.local/",
+		"mqtt://broker.local/home/+/share",
+		"mqtt://broker.local/home/#",
+		"mqtt://broker.local/topic?tls-ca=/ca.pem",
+		"mqtts://broker.local/topic?tls-cert=/client.pem",
+		"mqtts://broker.local/topic?tls-ca=/nonexistent/ca.pem",
+	} {
+		_, err := parseOptions(invalid)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", invalid)
+		}
+	}
+}
+
+type testPKI struct {
+	serverTLS  *tls.Config
+	caFile     string
+	clientCert string
+	clientKey  string
+}
+
+// newPKI creates a CA, a server certificate for 127.0.0.1 and a client certificate.
+func newPKI(t *testing.T, dir string) testPKI {
+	t.Helper()
+
+	caKey := newKey(t)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER := createCert(t, caTemplate, caTemplate, caKey, caKey)
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	serverKey := newKey(t)
+	serverDER := createCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "broker"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caCert, serverKey, caKey)
+
+	clientKey := newKey(t)
+	clientDER := createCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "unraid"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caCert, clientKey, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+
+	return testPKI{
+		serverTLS: &tls.Config{
+			Certificates: []tls.Certificate{{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}},
+			ClientCAs:    pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			MinVersion:   tls.VersionTLS12,
+		},
+		caFile:     writePEM(t, dir, "ca.pem", "CERTIFICATE", caDER),
+		clientCert: writePEM(t, dir, "client.pem", "CERTIFICATE", clientDER),
+		clientKey:  writePEM(t, dir, "client.key", "EC PRIVATE KEY", marshalKey(t, clientKey)),
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	return key
+}
+
+func createCert(
+	t *testing.T,
+	template *x509.Certificate,
+	parent *x509.Certificate,
+	key *ecdsa.PrivateKey,
+	signer *ecdsa.PrivateKey,
+) []byte {
+	t.Helper()
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return der
+}
+
+func marshalKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	return der
+}
+
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+
+	return path
+}
@@ -0,0 +1,120 @@
+package secrets
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+)
+
+// PlannedLocation describes how unlocking would fetch a location.
+type PlannedLocation struct {
+	Location int    `json:"location"`
+	Name     string `json:"name,omitempty"`
+	Group    string `json:"group,omitempty"`
+	Priority int    `json:"priority"`
+	// Deferred is set when the location is only fetched if the locations of lower
+	// priority cannot provide enough shares.
+	Deferred bool `json:"deferred"`
+	// Queued is set when the location waits for a free slot under max-concurrency.
+	Queued       bool  `json:"queued,omitempty"`
+	TimeoutMS    int64 `json:"timeoutMs"`
+	BackoffMS    int64 `json:"backoffMs"`
+	MaxBackoffMS int64 `json:"maxBackoffMs"`
+	// MaxAttempts is 0 when the location is retried until the threshold or deadline.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// Targets are the fallback chain, checked as by ValidateConfig.
+	Targets []TargetReport `json:"targets"`
+}
+
+// PlanLocations returns the locations in the order unlocking would start them, with the
+// fetcher of each target and the timeout and retries that apply. No location is
+// contacted. have counts the shares available beforehand, such as the recovery share, and
+// maxConcurrency limits the locations started at once (0: no limit).
+func PlanLocations(
+	locations []config.Location,
+	have int,
+	threshold int,
+	maxConcurrency int,
+	retryDelay time.Duration,
+	serverTimeout time.Duration,
+	test bool,
+) []PlannedLocation {
+	var (
+		plan      = make([]PlannedLocation, 0, len(locations))
+		order     = preferenceOrder(locations)
+		started   int
+		potential = have
+		lower     = have
+	)
+
+	// As in dueLocations, before any fetch has failed.
+	for i, pathNum := range order {
+		location := locations[pathNum]
+
+		if i > 0 && location.Priority != locations[order[i-1]].Priority {
+			lower = potential
+		}
+
+		timeout := serverTimeout
+		if location.Timeout > 0 {
+			timeout = time.Duration(location.Timeout)
+		}
+
+		base, limit := backoffRange(location, retryDelay)
+
+		planned := PlannedLocation{
+			Location:     pathNum,
+			Name:         location.Name,
+			Group:        location.Group,
+			Priority:     location.Priority,
+			Deferred:     lower >= threshold && !test,
+			TimeoutMS:    timeout.Milliseconds(),
+			BackoffMS:    base.Milliseconds(),
+			MaxBackoffMS: limit.Milliseconds(),
+			MaxAttempts:  location.MaxAttempts,
+		}
+
+		for mirror, path := range location.Mirrors() {
+			target, _ := validateTarget(path, location.Params)
+			target.Location = pathNum
+			target.Mirror = mirror
+			target.Name = location.Name
+
+			planned.Targets = append(planned.Targets, target)
+		}
+
+		plan = append(plan, planned)
+
+		if planned.Deferred {
+			continue
+		}
+
+		if maxConcurrency > 0 && started >= maxConcurrency {
+			plan[len(plan)-1].Queued = true
+		} else {
+			started++
+		}
+
+		potential++
+	}
+
+	return plan
+}
@@ -0,0 +1,105 @@
+package secrets
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+)
+
+// Testing objectives:
+// - Verify that locations are planned in order of priority with their fetchers.
+// - Verify that location timeouts and backoff override the defaults.
+// - Verify that higher priorities are deferred unless needed, counting shares already held.
+// - Ensure that test mode fetches every location.
+// - Verify that locations beyond max-concurrency are queued rather than started.
+
+// TestPlanLocations tests the order, fetchers, timeouts and deferral.
+func TestPlanLocations(t *testing.T) {
+	locations := []config.Location{
+		{URL: "https://cloud/share", Options: config.Options{Priority: 10, Timeout: config.Duration(time.Minute)}},
+		{URL: "https://nas/share", Fallback: []string{"dns:share.example.com"}},
+		{
+			URL: "dns:backup.example.com",
+			Options: config.Options{
+				Backoff:     config.Duration(5 * time.Second),
+				MaxBackoff:  config.Duration(time.Minute),
+				MaxAttempts: 3,
+			},
+		},
+	}
+
+	plan := PlanLocations(locations, 0, 2, 0, 30*time.Second, 10*time.Second, false)
+
+	if len(plan) != 3 || plan[0].Location != 1 || plan[1].Location != 2 || plan[2].Location != 0 {
+		t.Fatalf("unexpected order: %+v", plan)
+	}
+
+	if len(plan[0].Targets) != 2 || plan[0].Targets[0].Fetcher != "http" || plan[0].Targets[1].Fetcher != "dns" {
+		t.Errorf("unexpected targets: %+v", plan[0].Targets)
+	}
+
+	if plan[0].TimeoutMS != 10000 || plan[0].BackoffMS != 30000 || plan[2].TimeoutMS != 60000 {
+		t.Errorf("unexpected timeouts: %+v", plan)
+	}
+
+	if plan[1].BackoffMS != 5000 || plan[1].MaxBackoffMS != 60000 || plan[1].MaxAttempts != 3 {
+		t.Errorf("unexpected retries: %+v", plan[1])
+	}
+
+	if plan[0].Deferred || plan[1].Deferred || !plan[2].Deferred {
+		t.Errorf("only the cloud location should be deferred: %+v", plan)
+	}
+
+	plan = PlanLocations(locations, 0, 3, 0, 30*time.Second, 10*time.Second, false)
+	if plan[2].Deferred {
+		t.Error("cloud location should be needed for a threshold of 3")
+	}
+
+	plan = PlanLocations(locations, 1, 3, 0, 30*time.Second, 10*time.Second, false)
+	if !plan[2].Deferred {
+		t.Error("cloud location should be deferred for a threshold of 3 with one share held")
+	}
+
+	plan = PlanLocations(locations, 0, 1, 0, 30*time.Second, 10*time.Second, true)
+	for _, location := range plan {
+		if location.Deferred {
+			t.Errorf("location %d deferred in test mode", location.Location)
+		}
+	}
+}
+
+// TestPlanLocations_MaxConcurrency tests queuing locations beyond the concurrency limit.
+func TestPlanLocations_MaxConcurrency(t *testing.T) {
+	locations := LocationsFromPaths([]string{"https://a/share", "https://b/share", "https://c/share"})
+	locations = append(locations, config.Location{URL: "https://cloud/share", Options: config.Options{Priority: 10}})
+
+	plan := PlanLocations(locations, 0, 3, 2, 30*time.Second, 10*time.Second, false)
+
+	if plan[0].Queued || plan[1].Queued || !plan[2].Queued {
+		t.Errorf("only the third location should be queued: %+v", plan)
+	}
+
+	// The queued location still counts toward the threshold, so the cloud is not needed.
+	if !plan[3].Deferred || plan[3].Queued {
+		t.Errorf("cloud location should be deferred: %+v", plan[3])
+	}
+}
@@ -22,6 +22,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	// Register rclone backends individually so that we can exclude backends that are known to not work.
@@ -87,6 +90,8 @@ import (
 	_ "github.com/rclone/rclone/backend/zoho"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/fspath"
 
 	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/registry"
 )
@@ -100,6 +105,9 @@ func init() {
 	registry.Register(&Fetcher{})
 }
 
+// locationType matches a leading URL scheme or type prefix such as "htps:" or "vault:".
+var locationType = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*):`)
+
 // Fetcher implements the secret fetching interface for rclone-based file retrieval.
 // Supports local files and remote backends (S3, SFTP, etc.).
 type Fetcher struct{}
@@ -115,6 +123,64 @@ func (f *Fetcher) Priority() int {
 	return PriorityRclone
 }
 
+func (f *Fetcher) Name() string {
+	return "rclone"
+}
+
+// Validate checks that the path is a connection string for a registered backend or an
+// absolute local path. As the catch-all, rclone also receives locations meant for other
+// fetchers whose type is mistyped or not supported, which would be read as local files.
+func (f *Fetcher) Validate(path string) error {
+	if !strings.HasPrefix(path, ":") {
+		if scheme := locationType.FindStringSubmatch(path); scheme != nil {
+			return fmt.Errorf("unknown location type %q would be read as a local file", scheme[1])
+		}
+
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("local path must be absolute: %s", path)
+		}
+
+		return nil
+	}
+
+	parsed, err := fspath.Parse(path)
+	if err != nil {
+		return fmt.Errorf("invalid backend path: %w", err)
+	}
+
+	backend := strings.TrimPrefix(parsed.Name, ":")
+
+	_, err = fs.Find(backend)
+	if err != nil {
+		return fmt.Errorf("unknown rclone backend %q: %w", backend, err)
+	}
+
+	if backend != "http" && !strings.Contains(parsed.Path, "/") {
+		return fmt.Errorf("backend path must include a directory and file name: %s", parsed.Path)
+	}
+
+	return nil
+}
+
+// ApplyParams adds params from the configuration file to the connection string as backend
+// options, e.g. pass for sftp. A local path has no backend to configure and is rejected.
+func (f *Fetcher) ApplyParams(path string, params map[string]string) (string, error) {
+	parsed, err := fspath.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid backend path: %w", err)
+	}
+
+	if parsed.Name == "" {
+		return "", fmt.Errorf("params are not supported for local paths: %s", path)
+	}
+
+	options := configmap.Simple{}
+	maps.Copy(options, params)
+	maps.Copy(options, parsed.Config)
+
+	return parsed.Name + "," + options.String() + ":" + parsed.Path, nil
+}
+
 // Fetch retrieves secret data using rclone from various backends.
 // Supports local files and remote backends (S3, SFTP, etc.).
 // Path format:
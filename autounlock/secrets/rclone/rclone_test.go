@@ -179,3 +179,59 @@ func TestSplitLocalPath(t *testing.T) {
 		})
 	}
 }
+
+// TestApplyParams tests adding params to connection strings as backend options.
+func TestApplyParams(t *testing.T) {
+	fetcher := &Fetcher{}
+	params := map[string]string{"user": "unraid", "key_file": "/root/.ssh/id_ed25519"}
+
+	got, err := fetcher.ApplyParams(":sftp,host=nas,user=admin:/shares/share.txt", params)
+	if err != nil {
+		t.Fatalf("ApplyParams failed: %v", err)
+	}
+
+	want := ":sftp,host='nas',key_file='/root/.ssh/id_ed25519',user='admin':/shares/share.txt"
+	if got != want {
+		t.Errorf("ApplyParams = %q, want %q", got, want)
+	}
+
+	_, err = fetcher.ApplyParams("/boot/share.txt", params)
+	if err == nil {
+		t.Error("expected error for local path, got none")
+	}
+}
+
+// TestValidate tests accepted connection strings and paths caught by the catch-all.
+func TestValidate(t *testing.T) {
+	fetcher := &Fetcher{}
+
+	valid := []string{
+		"/boot/config/share.txt",
+		":sftp,host=nas:/share/file",
+		":s3,provider=AWS:bucket/share",
+		":http,url='https://nas/share':",
+	}
+
+	for _, path := range valid {
+		err := fetcher.Validate(path)
+		if err != nil {
+			t.Errorf("Validate(%q) failed: %v", path, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"htps://nas/share":       "unknown location type",
+		"vault:secret/share":     "unknown location type",
+		"relative/share":         "must be absolute",
+		":nosuch:/bucket/share":  "unknown rclone backend",
+		":sftp,host=nas:share":   "directory and file name",
+		":sftp,host='nas:/share": "invalid backend path",
+	}
+
+	for path, want := range invalid {
+		err := fetcher.Validate(path)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate(%q) = %v, want error containing %q", path, err, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package redis
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// Testing objectives:
+// - Verify that a key is read with and without an ACL user.
+// - Verify that rediss:// connects using a custom CA.
+// - Ensure that missing keys, wrong credentials and invalid URLs are reported.
+
+func fetch(location string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return (&Fetcher{}).Fetch(ctx, location)
+}
+
+// TestFetch_Key tests reading a key from a selected database.
+func TestFetch_Key(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.Select(2)
+	server.Set("unraid:share", "redis-share\n")
+
+	got, err := fetch("redis://" + server.Addr() + "/2?key=unraid:share")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if got != "redis-share" {
+		t.Errorf("share = %q, want %q", got, "redis-share")
+	}
+
+	_, err = fetch("redis://" + server.Addr() + "/2?key=unraid:missing")
+	if err == nil {
+		t.Error("expected error for missing key, got none")
+	}
+}
+
+// TestFetch_ACLUser tests authentication with an ACL user.
+func TestFetch_ACLUser(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("unraid", "s3cret")
+	server.Set("share", "acl-share")
+
+	got, err := fetch("redis://unraid:s3cret@" + server.Addr() + "?key=share")
+	if err != nil || got != "acl-share" {
+		t.Errorf("Fetch() = %q, %v; want %q", got, err, "acl-share")
+	}
+
+	_, err = fetch("redis://unraid:wrong@" + server.Addr() + "?key=share")
+	if err == nil {
+		t.Error("expected error for wrong password, got none")
+	}
+}
+
+// TestFetch_TLS tests rediss:// with a custom CA bundle.
+func TestFetch_TLS(t *testing.T) {
+	cert, caFile := newServerCert(t)
+
+	server, err := miniredis.RunTLS(&tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		t.Fatalf("failed to start TLS server: %v", err)
+	}
+	defer server.Close()
+
+	server.Set("share", "tls-share")
+
+	got, err := fetch("rediss://" + server.Addr() + "?key=share&tls-ca=" + caFile)
+	if err != nil || got != "tls-share" {
+		t.Errorf("Fetch() = %q, %v; want %q", got, err, "tls-share")
+	}
+
+	_, err = fetch("rediss://" + server.Addr() + "?key=share")
+	if err == nil {
+		t.Error("expected error for untrusted certificate, got none")
+	}
+}
+
+// TestParseOptions_Invalid tests that malformed Redis URLs are rejected.
+func TestParseOptions_Invalid(t *testing.T) {
+	for _, location := range []string{
+		"redis://localhost:6379",
+		"redis://localhost:6379?key=share&tls-ca=/ca.pem",
+		"redis://localhost:6379/notadb?key=share",
+		"rediss://localhost?key=share&tls-cert=/client.pem",
+	} {
+		_, _, err := parseOptions(location)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", location)
+		}
+	}
+}
+
+// newServerCert creates a self-signed certificate for 127.0.0.1 and writes it as a CA bundle.
+func newServerCert(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "redis"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+
+	err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write CA bundle: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
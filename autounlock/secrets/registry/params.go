@@ -0,0 +1,73 @@
+package registry
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParamsApplier is implemented by fetchers that accept per-location params from the
+// structured configuration file. Fetchers that do not implement it reject params.
+type ParamsApplier interface {
+	// ApplyParams returns the path with the params added in the fetcher's own syntax.
+	// Values already present in the path take precedence.
+	ApplyParams(path string, params map[string]string) (string, error)
+}
+
+// MergeQuery adds params to the query string of a URL location, leaving the parameters
+// already in the URL untouched. It implements ApplyParams for URL-based fetchers.
+func MergeQuery(path string, params map[string]string) (string, error) {
+	base, fragment, hasFragment := strings.Cut(path, "#")
+
+	parsed, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	existing := parsed.Query()
+	extra := url.Values{}
+
+	for key, value := range params {
+		if !existing.Has(key) {
+			extra.Set(key, value)
+		}
+	}
+
+	if len(extra) == 0 {
+		return path, nil
+	}
+
+	// Append rather than re-encode so that the original query is passed on unchanged.
+	switch {
+	case parsed.RawQuery != "":
+		base += "&"
+	case !strings.HasSuffix(base, "?"):
+		base += "?"
+	}
+
+	base += extra.Encode()
+
+	if hasFragment {
+		base += "#" + fragment
+	}
+
+	return base, nil
+}
@@ -0,0 +1,66 @@
+package registry
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import "testing"
+
+// Testing objectives:
+// - Verify that MergeQuery adds params without changing the existing query or fragment.
+// - Ensure that parameters already in the URL take precedence.
+
+// TestMergeQuery tests merging params into URL locations.
+func TestMergeQuery(t *testing.T) {
+	testCases := []struct {
+		path   string
+		params map[string]string
+		want   string
+	}{
+		{"https://nas/share", map[string]string{"tls-ca": "/ca.pem"}, "https://nas/share?tls-ca=%2Fca.pem"},
+		{"https://nas/share?", map[string]string{"a": "1"}, "https://nas/share?a=1"},
+		{
+			"https://nas/share?tls-pin=sha256/a+b#$.data",
+			map[string]string{"tls-pin": "other", "sig-key": "/k"},
+			"https://nas/share?tls-pin=sha256/a+b&sig-key=%2Fk#$.data",
+		},
+		{
+			"mdns:Tower._autounlock._tcp/share?tls-pin=x",
+			map[string]string{"sig-key": "/k"},
+			"mdns:Tower._autounlock._tcp/share?tls-pin=x&sig-key=%2Fk",
+		},
+		{"https://nas/share?a=1", map[string]string{"a": "2"}, "https://nas/share?a=1"},
+	}
+
+	for _, tc := range testCases {
+		got, err := MergeQuery(tc.path, tc.params)
+		if err != nil {
+			t.Errorf("MergeQuery(%q) failed: %v", tc.path, err)
+
+			continue
+		}
+
+		if got != tc.want {
+			t.Errorf("MergeQuery(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+
+	_, err := MergeQuery("https://nas:port/share", map[string]string{"a": "1"})
+	if err == nil {
+		t.Error("expected error for invalid URL, got none")
+	}
+}
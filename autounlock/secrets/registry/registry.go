@@ -34,6 +34,20 @@ type Fetcher interface {
 	// Priority returns the priority of this fetcher (lower number = higher priority).
 	// Multiple fetchers with the same priority can run in any order.
 	Priority() int
+	// Name returns the short name of this fetcher, used in reports.
+	Name() string
+}
+
+// Validator is implemented by fetchers that can check the syntax of a location, and the
+// local files it refers to, without contacting it.
+type Validator interface {
+	Validate(path string) error
+}
+
+// Interactive is implemented by fetchers whose fetch involves a person or hardware, such
+// as asking for approval or mounting a drive, which health checks only do when asked to.
+type Interactive interface {
+	Interactive() bool
 }
 
 var (
@@ -43,6 +43,10 @@ func (m *mockFetcher) Priority() int {
 	return m.priority
 }
 
+func (m *mockFetcher) Name() string {
+	return m.name
+}
+
 // TestRegister_SingleFetcher tests registering a single fetcher.
 func TestRegister_SingleFetcher(t *testing.T) {
 	// Save and restore original state
@@ -0,0 +1,184 @@
+package secrets
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+)
+
+// retrySchedule tracks the attempts made at one location and when it may be tried again.
+type retrySchedule struct {
+	attempts int
+	next     time.Time
+	inFlight bool
+	done     bool
+}
+
+// nextRetry returns the earliest time after now at which a location that is neither done
+// nor being fetched may be tried again. Locations that are already due but held back by
+// their priority or the concurrency limit are reconsidered when a fetch completes.
+func nextRetry(schedule []retrySchedule, now time.Time) (time.Time, bool) {
+	var (
+		next    time.Time
+		pending bool
+	)
+
+	for _, entry := range schedule {
+		if entry.done || entry.inFlight || !entry.next.After(now) {
+			continue
+		}
+
+		if !pending || entry.next.Before(next) {
+			next = entry.next
+			pending = true
+		}
+	}
+
+	return next, pending
+}
+
+// preferenceOrder returns the location indexes sorted by priority, keeping the
+// configured order within a priority.
+func preferenceOrder(locations []config.Location) []int {
+	order := make([]int, len(locations))
+	for i := range order {
+		order[i] = i
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(locations[a].Priority, locations[b].Priority)
+	})
+
+	return order
+}
+
+// dueLocations returns the locations to fetch now, in order of preference. A priority is
+// only started when the shares collected plus the locations of lower priorities that may
+// still provide one (in flight or not yet tried) cannot meet the threshold; a location
+// that failed stops counting, so an unreachable LAN server does not hold back the cloud
+// backups while it is retried. At most maxConcurrency fetches are in flight (0: no limit).
+// In test mode every location is tried regardless of priority.
+func dueLocations(
+	locations []config.Location,
+	schedule []retrySchedule,
+	order []int,
+	have int,
+	threshold int,
+	maxConcurrency int,
+	test bool,
+	now time.Time,
+) []int {
+	var (
+		due       []int
+		inFlight  int
+		potential = have
+		lower     = have
+	)
+
+	for _, entry := range schedule {
+		if entry.inFlight {
+			inFlight++
+		}
+	}
+
+	for i, pathNum := range order {
+		if i > 0 && locations[pathNum].Priority != locations[order[i-1]].Priority {
+			lower = potential
+		}
+
+		entry := schedule[pathNum]
+
+		switch {
+		case entry.done:
+			continue
+		case entry.inFlight:
+			potential++
+
+			continue
+		case now.Before(entry.next):
+			continue
+		}
+
+		if lower >= threshold && !test {
+			continue
+		}
+
+		if maxConcurrency > 0 && inFlight >= maxConcurrency {
+			// Not yet tried: it still counts toward the higher priorities' decision.
+			if entry.attempts == 0 {
+				potential++
+			}
+
+			continue
+		}
+
+		due = append(due, pathNum)
+		inFlight++
+		potential++
+	}
+
+	return due
+}
+
+// backoff returns the delay before retrying a location after the given number of failed
+// attempts: the location's backoff (or retryDelay) doubled for each further failure and
+// capped at its max-backoff. Up to half of the delay is randomized so that retries of
+// locations on the same server do not line up.
+func backoff(location config.Location, retryDelay time.Duration, failures int) time.Duration {
+	base, limit := backoffRange(location, retryDelay)
+
+	delay := base
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+
+	delay = min(delay, limit)
+	if delay < 2 {
+		return delay
+	}
+
+	half := delay / 2
+
+	return half + rand.N(delay-half) //nolint:gosec // Jitter does not need a secure source
+}
+
+// backoffRange returns the delay before the first retry of a location and the cap of its
+// later retries, before jitter.
+func backoffRange(
+	location config.Location,
+	retryDelay time.Duration,
+) (time.Duration, time.Duration) {
+	base := time.Duration(location.Backoff)
+	if base <= 0 {
+		base = retryDelay
+	}
+
+	limit := time.Duration(location.MaxBackoff)
+	if limit <= 0 {
+		limit = max(constants.MaxRetryBackoff, base)
+	}
+
+	return base, limit
+}
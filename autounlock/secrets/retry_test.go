@@ -0,0 +1,167 @@
+package secrets
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+)
+
+// Testing objectives:
+// - Verify that the backoff doubles per failure within the jitter range and respects the cap.
+// - Verify that the retry delay is used when a location sets no backoff.
+// - Verify that nextRetry ignores settled locations and those already due.
+// - Verify that lower priorities are fetched first and higher ones only when needed.
+// - Verify that a failed location no longer holds back the next priority.
+// - Verify that the concurrency limit is respected.
+
+// TestBackoff tests exponential growth, jitter range and the cap.
+func TestBackoff(t *testing.T) {
+	location := config.Location{
+		Options: config.Options{
+			Backoff:    config.Duration(time.Second),
+			MaxBackoff: config.Duration(5 * time.Second),
+		},
+	}
+
+	testCases := []struct {
+		failures int
+		low      time.Duration
+		high     time.Duration
+	}{
+		{1, 500 * time.Millisecond, time.Second},
+		{2, time.Second, 2 * time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+		{10, 2500 * time.Millisecond, 5 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		for range 20 {
+			got := backoff(location, time.Hour, tc.failures)
+			if got < tc.low || got > tc.high {
+				t.Fatalf("backoff after %d failures = %v, want [%v, %v]", tc.failures, got, tc.low, tc.high)
+			}
+		}
+	}
+}
+
+// TestBackoff_Defaults tests the retry delay and default cap.
+func TestBackoff_Defaults(t *testing.T) {
+	got := backoff(config.Location{}, time.Minute, 1)
+	if got < 30*time.Second || got > time.Minute {
+		t.Errorf("backoff = %v, want within the retry delay", got)
+	}
+
+	got = backoff(config.Location{}, time.Minute, 100)
+	if got > constants.MaxRetryBackoff {
+		t.Errorf("backoff = %v, want at most %v", got, constants.MaxRetryBackoff)
+	}
+}
+
+// TestNextRetry tests finding the earliest pending retry.
+func TestNextRetry(t *testing.T) {
+	now := time.Now()
+
+	next, pending := nextRetry([]retrySchedule{
+		{next: now.Add(-time.Hour), done: true},
+		{next: now.Add(-time.Minute)},
+		{next: now.Add(2 * time.Second)},
+		{next: now.Add(time.Second)},
+	}, now)
+	if !pending || !next.Equal(now.Add(time.Second)) {
+		t.Errorf("nextRetry = %v, %v", next, pending)
+	}
+
+	_, pending = nextRetry([]retrySchedule{{done: true}, {}}, now)
+	if pending {
+		t.Error("expected no pending retry")
+	}
+}
+
+func prioritized(priorities ...int) []config.Location {
+	locations := make([]config.Location, len(priorities))
+	for i, priority := range priorities {
+		locations[i].Priority = priority
+	}
+
+	return locations
+}
+
+// TestDueLocations_Priority tests that higher priorities wait until they are needed.
+func TestDueLocations_Priority(t *testing.T) {
+	now := time.Now()
+	locations := prioritized(10, 0, 0, 20)
+	order := preferenceOrder(locations)
+	schedule := make([]retrySchedule, len(locations))
+
+	due := dueLocations(locations, schedule, order, 0, 2, 0, false, now)
+	if !slices.Equal(due, []int{1, 2}) {
+		t.Fatalf("due = %v, want [1 2]", due)
+	}
+
+	for _, pathNum := range due {
+		schedule[pathNum].inFlight = true
+	}
+
+	// Both are still in flight and may meet the threshold.
+	due = dueLocations(locations, schedule, order, 0, 2, 0, false, now)
+	if len(due) != 0 {
+		t.Fatalf("due = %v, want none", due)
+	}
+
+	// One failed and backs off: the next priority is started, but not the last one.
+	schedule[1] = retrySchedule{attempts: 1, next: now.Add(time.Minute)}
+
+	due = dueLocations(locations, schedule, order, 0, 2, 0, false, now)
+	if !slices.Equal(due, []int{0}) {
+		t.Fatalf("due = %v, want [0]", due)
+	}
+
+	// Test mode tries everything.
+	due = dueLocations(locations, make([]retrySchedule, len(locations)), order, 0, 2, 0, true, now)
+	if !slices.Equal(due, []int{1, 2, 0, 3}) {
+		t.Fatalf("due = %v, want [1 2 0 3]", due)
+	}
+}
+
+// TestDueLocations_Concurrency tests the limit on fetches in flight.
+func TestDueLocations_Concurrency(t *testing.T) {
+	now := time.Now()
+	locations := prioritized(0, 0, 0, 1)
+	order := preferenceOrder(locations)
+	schedule := make([]retrySchedule, len(locations))
+
+	due := dueLocations(locations, schedule, order, 0, 3, 2, false, now)
+	if !slices.Equal(due, []int{0, 1}) {
+		t.Fatalf("due = %v, want [0 1]", due)
+	}
+
+	schedule[0].inFlight = true
+	schedule[1] = retrySchedule{attempts: 1, done: true}
+
+	// The untried location of the same priority takes the free slot first.
+	due = dueLocations(locations, schedule, order, 0, 3, 2, false, now)
+	if !slices.Equal(due, []int{2}) {
+		t.Fatalf("due = %v, want [2]", due)
+	}
+}
@@ -19,6 +19,7 @@ package secrets
 */
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 
@@ -35,16 +36,30 @@ type Service struct {
 	execPath string
 	debug    bool
 	pretty   bool
+	// deviceKey is passed on to fetch-share for fetchers that sign requests.
+	deviceKey string
+
+	// fetch retrieves a single location. It is the fetch-share subprocess except in tests.
+	fetch func(ctx context.Context, path string) (string, error)
 }
 
 // NewService creates a new secrets service.
 func NewService(fs afero.Fs, execPath string, debug bool, pretty bool) *Service {
-	return &Service{
+	svc := &Service{
 		fs:       fs,
 		execPath: execPath,
 		debug:    debug,
 		pretty:   pretty,
 	}
+	svc.fetch = svc.fetchShare
+
+	return svc
+}
+
+// SetDeviceKey sets the device key that fetchers sign requests with when a location does
+// not name one.
+func (s *Service) SetDeviceKey(path string) {
+	s.deviceKey = path
 }
 
 // SharedSecret represents a shared secret with all its components.
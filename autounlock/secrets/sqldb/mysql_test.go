@@ -0,0 +1,332 @@
+package sqldb
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1" //nolint:gosec // Required by mysql_native_password
+	"encoding/binary"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Testing objectives:
+// - Verify that the MySQL fetcher returns the single value selected by the query.
+// - Verify that the query runs in a read-only transaction.
+// - Ensure that wrong credentials and results that are not a single value are rejected.
+// - Ensure that unsupported connection parameters are rejected.
+
+const (
+	mysqlCapabilities = 0x00000001 | // CLIENT_LONG_PASSWORD
+		0x00000008 | // CLIENT_CONNECT_WITH_DB
+		0x00000200 | // CLIENT_PROTOCOL_41
+		0x00002000 | // CLIENT_TRANSACTIONS
+		0x00008000 | // CLIENT_SECURE_CONNECTION
+		0x00080000 // CLIENT_PLUGIN_AUTH
+	mysqlNativePassword = "mysql_native_password"
+)
+
+// mysqlStandIn is a minimal MySQL server supporting mysql_native_password and text queries.
+type mysqlStandIn struct {
+	user     string
+	password string
+	results  map[string]result
+
+	mu      sync.Mutex
+	queries []string
+}
+
+func startMySQL(t *testing.T, results map[string]result) (*mysqlStandIn, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	stand := &mysqlStandIn{user: "unraid", password: "s3cret", results: results}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go stand.serve(conn)
+		}
+	}()
+
+	return stand, listener.Addr().String()
+}
+
+// mysqlConn reads and writes MySQL packets, tracking the sequence number.
+type mysqlConn struct {
+	reader *bufio.Reader
+	writer io.Writer
+	seq    byte
+}
+
+func (c *mysqlConn) read() ([]byte, error) {
+	header := make([]byte, 4)
+
+	_, err := io.ReadFull(c.reader, header)
+	if err != nil {
+		return nil, err
+	}
+
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	c.seq = header[3] + 1
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+
+	return payload, err
+}
+
+func (c *mysqlConn) write(payload []byte) {
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), c.seq}
+	c.seq++
+
+	c.writer.Write(append(header, payload...))
+}
+
+func (c *mysqlConn) ok() {
+	c.write([]byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00})
+}
+
+func (c *mysqlConn) eof() {
+	c.write([]byte{0xfe, 0x00, 0x00, 0x02, 0x00})
+}
+
+func (c *mysqlConn) err(code uint16, state string, message string) {
+	payload := []byte{0xff, byte(code), byte(code >> 8), '#'}
+	payload = append(payload, state...)
+	c.write(append(payload, message...))
+}
+
+func lengthEncoded(data []byte) []byte {
+	return append([]byte{byte(len(data))}, data...)
+}
+
+func scramble(salt []byte, password string) []byte {
+	if password == "" {
+		return nil
+	}
+
+	stage1 := sha1.Sum([]byte(password))
+	stage2 := sha1.Sum(stage1[:])
+	hash := sha1.Sum(append(append([]byte{}, salt...), stage2[:]...))
+
+	for i := range hash {
+		hash[i] ^= stage1[i]
+	}
+
+	return hash[:]
+}
+
+func (s *mysqlStandIn) serve(netConn net.Conn) {
+	defer netConn.Close()
+
+	conn := &mysqlConn{reader: bufio.NewReader(netConn), writer: netConn}
+	salt := []byte("abcdefghijklmnopqrst")
+
+	handshake := []byte{0x0a}
+	handshake = append(handshake, "8.0.0-standin\x00"...)
+	handshake = append(handshake, 1, 0, 0, 0)
+	handshake = append(handshake, salt[:8]...)
+	handshake = append(handshake, 0x00)
+	handshake = binary.LittleEndian.AppendUint16(handshake, uint16(mysqlCapabilities&0xffff))
+	handshake = append(handshake, 0x21, 0x02, 0x00)
+	handshake = binary.LittleEndian.AppendUint16(handshake, uint16(mysqlCapabilities>>16))
+	handshake = append(handshake, 21)
+	handshake = append(handshake, make([]byte, 10)...)
+	handshake = append(handshake, salt[8:]...)
+	handshake = append(handshake, 0x00)
+	handshake = append(handshake, mysqlNativePassword+"\x00"...)
+	conn.write(handshake)
+
+	response, err := conn.read()
+	if err != nil || len(response) < 33 {
+		return
+	}
+
+	rest := response[32:]
+	user, rest, _ := bytes.Cut(rest, []byte{0})
+
+	authLength := int(rest[0])
+	auth := rest[1 : 1+authLength]
+
+	if string(user) != s.user || !bytes.Equal(auth, scramble(salt, s.password)) {
+		conn.err(1045, "28000", "Access denied")
+
+		return
+	}
+
+	conn.ok()
+
+	for {
+		packet, err := conn.read()
+		if err != nil || len(packet) == 0 || packet[0] != 0x03 {
+			return
+		}
+
+		query := string(packet[1:])
+
+		s.mu.Lock()
+		s.queries = append(s.queries, query)
+		s.mu.Unlock()
+
+		s.respond(conn, query)
+	}
+}
+
+func (s *mysqlStandIn) respond(conn *mysqlConn, query string) {
+	upper := strings.ToUpper(query)
+	if strings.HasPrefix(upper, "START TRANSACTION") || strings.HasPrefix(upper, "ROLLBACK") {
+		conn.ok()
+
+		return
+	}
+
+	res, ok := s.results[query]
+	if !ok {
+		conn.err(1146, "42S02", "Table doesn't exist")
+
+		return
+	}
+
+	conn.write([]byte{byte(len(res.columns))})
+
+	for _, column := range res.columns {
+		definition := lengthEncoded([]byte("def"))
+		definition = append(definition, lengthEncoded([]byte("secrets"))...)
+		definition = append(definition, lengthEncoded([]byte("unlock"))...)
+		definition = append(definition, lengthEncoded([]byte("unlock"))...)
+		definition = append(definition, lengthEncoded([]byte(column))...)
+		definition = append(definition, lengthEncoded([]byte(column))...)
+		definition = append(definition, 0x0c, 0x21, 0x00, 0xff, 0xff, 0x00, 0x00, 0xfd, 0x00, 0x00, 0x00, 0x00, 0x00)
+		conn.write(definition)
+	}
+
+	conn.eof()
+
+	for _, row := range res.rows {
+		var payload []byte
+
+		for _, v := range row {
+			if v == nil {
+				payload = append(payload, 0xfb)
+			} else {
+				payload = append(payload, lengthEncoded([]byte(*v))...)
+			}
+		}
+
+		conn.write(payload)
+	}
+
+	conn.eof()
+}
+
+func fetchMySQL(address string, credentials string, query string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	location := "mysql://" + credentials + "@" + address + "/secrets?query=" + url.QueryEscape(query)
+
+	return (&MySQLFetcher{}).Fetch(ctx, location)
+}
+
+// TestMySQLFetcher_Value tests that the selected value is returned from a read-only transaction.
+func TestMySQLFetcher_Value(t *testing.T) {
+	stand, address := startMySQL(t, sqlResults)
+
+	got, err := fetchMySQL(address, "unraid:s3cret", shareQuery)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if got != "sql-share" {
+		t.Errorf("share = %q, want %q", got, "sql-share")
+	}
+
+	stand.mu.Lock()
+	defer stand.mu.Unlock()
+
+	if len(stand.queries) == 0 || !strings.Contains(strings.ToUpper(stand.queries[0]), "READ ONLY") {
+		t.Errorf("expected a read-only transaction first, got queries %v", stand.queries)
+	}
+}
+
+// TestMySQLFetcher_Failures tests credentials and results that are not a single value.
+func TestMySQLFetcher_Failures(t *testing.T) {
+	_, address := startMySQL(t, sqlResults)
+
+	_, err := fetchMySQL(address, "unraid:wrong", shareQuery)
+	if err == nil {
+		t.Error("expected error for wrong password, got none")
+	}
+
+	for _, query := range []string{
+		"SELECT none",
+		"SELECT many",
+		"SELECT two_columns",
+		"SELECT null_value",
+		"SELECT empty_value",
+		"SELECT missing",
+	} {
+		_, err := fetchMySQL(address, "unraid:s3cret", query)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", query)
+		}
+	}
+}
+
+// TestParseMySQLConfig tests URL conversion and parameter validation.
+func TestParseMySQLConfig(t *testing.T) {
+	config, query, err := parseMySQLConfig("mysql://u:p@db.local/secrets?tls=true&query=SELECT+1")
+	if err != nil {
+		t.Fatalf("parseMySQLConfig failed: %v", err)
+	}
+
+	if query != "SELECT 1" || config.Addr != "db.local:3306" || config.DBName != "secrets" ||
+		config.User != "u" || config.Passwd != "p" || config.TLS == nil || config.TLS.ServerName != "db.local" {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	for _, invalid := range []string{
+		"mysql://db.local/secrets?tls=skip-verify&query=SELECT+1",
+		"mysql://db.local/secrets?parseTime=true&query=SELECT+1",
+		"mysql://db.local/secrets?tls-cert=/client.pem&query=SELECT+1",
+	} {
+		_, _, err := parseMySQLConfig(invalid)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", invalid)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package sqldb
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// Testing objectives:
+// - Verify that the PostgreSQL fetcher returns the single value selected by the query.
+// - Verify that the query runs in a read-only transaction.
+// - Ensure that wrong credentials and results that are not a single value are rejected.
+
+// result is a canned query result for the database stand-ins. A nil value is NULL.
+type result struct {
+	columns []string
+	rows    [][]*string
+}
+
+func value(s string) *string {
+	return &s
+}
+
+// pgStandIn is a minimal PostgreSQL server speaking the simple query protocol.
+type pgStandIn struct {
+	user     string
+	password string
+	results  map[string]result
+
+	mu      sync.Mutex
+	queries []string
+}
+
+func startPostgres(t *testing.T, results map[string]result) (*pgStandIn, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	stand := &pgStandIn{user: "unraid", password: "s3cret", results: results}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go stand.serve(conn)
+		}
+	}()
+
+	return stand, listener.Addr().String()
+}
+
+func (s *pgStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+
+	backend := pgproto3.NewBackend(conn, conn)
+
+	startup, err := backend.ReceiveStartupMessage()
+	if _, ok := startup.(*pgproto3.SSLRequest); ok {
+		conn.Write([]byte("N"))
+		startup, err = backend.ReceiveStartupMessage()
+	}
+
+	params, ok := startup.(*pgproto3.StartupMessage)
+	if err != nil || !ok {
+		return
+	}
+
+	backend.Send(&pgproto3.AuthenticationCleartextPassword{})
+	backend.Flush()
+	backend.SetAuthType(pgproto3.AuthTypeCleartextPassword)
+
+	msg, err := backend.Receive()
+	password, ok := msg.(*pgproto3.PasswordMessage)
+
+	if err != nil || !ok || params.Parameters["user"] != s.user || password.Password != s.password {
+		backend.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: "28P01", Message: "password authentication failed"})
+		backend.Flush()
+
+		return
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "server_version", Value: "16.0"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: []byte{0, 0, 0, 1}})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	backend.Flush()
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+
+		query, ok := msg.(*pgproto3.Query)
+		if !ok {
+			return
+		}
+
+		s.mu.Lock()
+		s.queries = append(s.queries, query.String)
+		s.mu.Unlock()
+
+		s.respond(backend, query.String)
+		backend.Flush()
+	}
+}
+
+func (s *pgStandIn) respond(backend *pgproto3.Backend, query string) {
+	lower := strings.ToLower(query)
+
+	switch {
+	case strings.HasPrefix(lower, "begin"):
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("BEGIN")})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'T'})
+
+		return
+	case strings.HasPrefix(lower, "rollback"):
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("ROLLBACK")})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+
+		return
+	}
+
+	res, ok := s.results[query]
+	if !ok {
+		backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "42P01", Message: "relation does not exist"})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'E'})
+
+		return
+	}
+
+	fields := make([]pgproto3.FieldDescription, 0, len(res.columns))
+	for _, column := range res.columns {
+		fields = append(fields, pgproto3.FieldDescription{Name: []byte(column), DataTypeOID: 25, DataTypeSize: -1})
+	}
+
+	backend.Send(&pgproto3.RowDescription{Fields: fields})
+
+	for _, row := range res.rows {
+		values := make([][]byte, 0, len(row))
+		for _, v := range row {
+			if v == nil {
+				values = append(values, nil)
+			} else {
+				values = append(values, []byte(*v))
+			}
+		}
+
+		backend.Send(&pgproto3.DataRow{Values: values})
+	}
+
+	backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT")})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'T'})
+}
+
+const shareQuery = "SELECT share FROM unlock WHERE host = 'tower'"
+
+var sqlResults = map[string]result{
+	shareQuery:           {columns: []string{"share"}, rows: [][]*string{{value("sql-share\n")}}},
+	"SELECT none":        {columns: []string{"share"}},
+	"SELECT many":        {columns: []string{"share"}, rows: [][]*string{{value("a")}, {value("b")}}},
+	"SELECT two_columns": {columns: []string{"a", "b"}, rows: [][]*string{{value("a"), value("b")}}},
+	"SELECT null_value":  {columns: []string{"share"}, rows: [][]*string{{nil}}},
+	"SELECT empty_value": {columns: []string{"share"}, rows: [][]*string{{value("  ")}}},
+}
+
+func fetchPostgres(address string, credentials string, query string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	location := "postgres://" + credentials + "@" + address + "/secrets?sslmode=disable&query=" + url.QueryEscape(query)
+
+	return (&PostgresFetcher{}).Fetch(ctx, location)
+}
+
+// TestPostgresFetcher_Value tests that the selected value is returned from a read-only transaction.
+func TestPostgresFetcher_Value(t *testing.T) {
+	stand, address := startPostgres(t, sqlResults)
+
+	got, err := fetchPostgres(address, "unraid:s3cret", shareQuery)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if got != "sql-share" {
+		t.Errorf("share = %q, want %q", got, "sql-share")
+	}
+
+	stand.mu.Lock()
+	defer stand.mu.Unlock()
+
+	if len(stand.queries) == 0 || !strings.Contains(strings.ToLower(stand.queries[0]), "read only") {
+		t.Errorf("expected a read-only transaction first, got queries %v", stand.queries)
+	}
+}
+
+// TestPostgresFetcher_Failures tests credentials and results that are not a single value.
+func TestPostgresFetcher_Failures(t *testing.T) {
+	_, address := startPostgres(t, sqlResults)
+
+	_, err := fetchPostgres(address, "unraid:wrong", shareQuery)
+	if err == nil {
+		t.Error("expected error for wrong password, got none")
+	}
+
+	for _, query := range []string{
+		"SELECT none",
+		"SELECT many",
+		"SELECT two_columns",
+		"SELECT null_value",
+		"SELECT empty_value",
+		"SELECT missing",
+	} {
+		_, err := fetchPostgres(address, "unraid:s3cret", query)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", query)
+		}
+	}
+}
+
+// TestSplitQuery tests that the query parameter is removed from the connection URL.
+func TestSplitQuery(t *testing.T) {
+	connURL, query, err := splitQuery("postgres://u:p@db.local/secrets?sslmode=verify-full&query=SELECT+1")
+	if err != nil {
+		t.Fatalf("splitQuery failed: %v", err)
+	}
+
+	if query != "SELECT 1" || connURL.String() != "postgres://u:p@db.local/secrets?sslmode=verify-full" {
+		t.Errorf("splitQuery() = %q, %q", connURL.String(), query)
+	}
+
+	for _, invalid := range []string{"postgres://db.local/secrets", "postgres:///secrets?query=SELECT+1"} {
+		_, _, err := splitQuery(invalid)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", invalid)
+		}
+	}
+}
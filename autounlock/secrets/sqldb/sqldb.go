@@ -0,0 +1,89 @@
+package sqldb
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/registry"
+)
+
+const (
+	// PrioritySQL is the priority for the database fetchers (explicit prefixes).
+	PrioritySQL = 24
+
+	paramQuery   = "query"
+	maxShareSize = 64 * 1024
+)
+
+var (
+	errNoRows       = errors.New("query returned no rows")
+	errMultipleRows = errors.New("query returned more than one row")
+)
+
+func init() {
+	registry.Register(&PostgresFetcher{})
+	registry.Register(&MySQLFetcher{})
+}
+
+// splitQuery parses the location and removes the query parameter, which holds the
+// SQL statement rather than a connection option.
+func splitQuery(path string) (*url.URL, string, error) {
+	parsedURL, err := url.Parse(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid database URL: %w", err)
+	}
+
+	if parsedURL.Hostname() == "" {
+		return nil, "", errors.New("database URL must include a host")
+	}
+
+	params := parsedURL.Query()
+
+	query := strings.TrimSpace(params.Get(paramQuery))
+	if query == "" {
+		return nil, "", errors.New("database URL must include a query parameter")
+	}
+
+	params.Del(paramQuery)
+	parsedURL.RawQuery = params.Encode()
+
+	return parsedURL, query, nil
+}
+
+// checkValue validates the single value returned by a query.
+func checkValue(value *string) (string, error) {
+	if value == nil {
+		return "", errors.New("query returned NULL")
+	}
+
+	share := strings.TrimSpace(*value)
+	if share == "" {
+		return "", errors.New("query returned an empty value")
+	}
+
+	if len(share) > maxShareSize {
+		return "", fmt.Errorf("query result exceeds %d bytes", maxShareSize)
+	}
+
+	return share, nil
+}
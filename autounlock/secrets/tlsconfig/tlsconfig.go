@@ -0,0 +1,120 @@
+package tlsconfig
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// Location parameters shared by fetchers that connect over TLS.
+const (
+	ParamCA   = "tls-ca"
+	ParamCert = "tls-cert"
+	ParamKey  = "tls-key"
+)
+
+// Has reports whether any TLS parameter is present in the query.
+func Has(query url.Values) bool {
+	return query.Has(ParamCA) || query.Has(ParamCert) || query.Has(ParamKey)
+}
+
+// Strip removes the TLS parameters from the query.
+func Strip(query url.Values) {
+	query.Del(ParamCA)
+	query.Del(ParamCert)
+	query.Del(ParamKey)
+}
+
+// Files are the CA bundle and client certificate named by the TLS parameters.
+type Files struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+// ParseFiles reads the TLS parameters from the query. tls-cert and tls-key must be used
+// together.
+func ParseFiles(query url.Values) (Files, error) {
+	files := Files{
+		CAFile:   query.Get(ParamCA),
+		CertFile: query.Get(ParamCert),
+		KeyFile:  query.Get(ParamKey),
+	}
+
+	if (files.CertFile == "") != (files.KeyFile == "") {
+		return Files{}, errors.New("tls-cert and tls-key must be used together")
+	}
+
+	return files, nil
+}
+
+// Apply makes config trust the CA bundle instead of the system roots, and present the
+// client certificate, if they are set.
+func (f Files) Apply(config *tls.Config) error {
+	if f.CAFile != "" {
+		pem, err := os.ReadFile(f.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA bundle: %s", f.CAFile)
+		}
+
+		config.RootCAs = pool
+	}
+
+	if f.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(f.CertFile, f.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return nil
+}
+
+// Build returns a client TLS configuration for serverName, trusting the tls-ca bundle
+// (or the system roots) and presenting the tls-cert/tls-key client certificate if set.
+func Build(serverName string, query url.Values) (*tls.Config, error) {
+	files, err := ParseFiles(query)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	err = files.Apply(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
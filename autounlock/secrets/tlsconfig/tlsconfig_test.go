@@ -0,0 +1,129 @@
+package tlsconfig
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Testing objectives:
+// - Verify that the CA bundle and client certificate are loaded.
+// - Ensure that missing files and incomplete certificate pairs are rejected.
+// - Verify that Strip removes only the TLS parameters.
+
+func writeCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	return certFile, keyFile
+}
+
+// TestBuild tests loading a CA bundle and client certificate.
+func TestBuild(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir())
+
+	config, err := Build("broker.local", url.Values{
+		ParamCA:   {certFile},
+		ParamCert: {certFile},
+		ParamKey:  {keyFile},
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if config.ServerName != "broker.local" || config.RootCAs == nil || len(config.Certificates) != 1 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	config, err = Build("broker.local", url.Values{})
+	if err != nil || config.RootCAs != nil || len(config.Certificates) != 0 {
+		t.Errorf("expected system roots without client certificate, got %+v, %v", config, err)
+	}
+}
+
+// TestBuild_Invalid tests missing files and incomplete certificate pairs.
+func TestBuild_Invalid(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir())
+
+	for name, query := range map[string]url.Values{
+		"missing CA":   {ParamCA: {"/nonexistent/ca.pem"}},
+		"CA not cert":  {ParamCA: {keyFile}},
+		"cert only":    {ParamCert: {certFile}},
+		"key only":     {ParamKey: {keyFile}},
+		"key mismatch": {ParamCert: {certFile}, ParamKey: {certFile}},
+	} {
+		_, err := Build("broker.local", query)
+		if err == nil {
+			t.Errorf("%s: expected error, got none", name)
+		}
+	}
+}
+
+// TestHasAndStrip tests detection and removal of the TLS parameters.
+func TestHasAndStrip(t *testing.T) {
+	query := url.Values{ParamCA: {"/ca.pem"}, "key": {"share"}}
+
+	if !Has(query) {
+		t.Error("expected TLS parameters to be detected")
+	}
+
+	Strip(query)
+
+	if Has(query) || query.Get("key") != "share" {
+		t.Errorf("unexpected query after Strip: %v", query)
+	}
+}
@@ -0,0 +1,244 @@
+package usb
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/registry"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/unraid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// PriorityUSB is the priority for the removable-media fetcher (explicit prefix).
+	PriorityUSB = 14
+
+	maxShareSize   = 64 * 1024
+	unmountTimeout = 10 * time.Second
+
+	lsblkPath  = "/bin/lsblk"
+	mountPath  = "/sbin/mount"
+	umountPath = "/sbin/umount"
+)
+
+func init() {
+	registry.Register(&Fetcher{})
+}
+
+// Runner executes external commands. It can be replaced for testing.
+type Runner interface {
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+type execRunner struct{}
+
+// Output returns stdout only, so that warnings do not corrupt the JSON printed by lsblk.
+// Stderr is kept for the error message.
+func (execRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return out, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	if err != nil {
+		return out, fmt.Errorf("%s failed: %w", name, err)
+	}
+
+	return out, nil
+}
+
+type Fetcher struct {
+	// Runner can be optionally set for testing. If nil, commands are executed directly.
+	Runner Runner
+}
+
+func (f *Fetcher) Match(path string) bool {
+	return strings.HasPrefix(path, "usb:")
+}
+
+func (f *Fetcher) Priority() int {
+	return PriorityUSB
+}
+
+func (f *Fetcher) Name() string {
+	return "usb"
+}
+
+// Interactive reports that fetching mounts a device.
+func (f *Fetcher) Interactive() bool {
+	return true
+}
+
+// Validate checks the location without connecting to it.
+func (f *Fetcher) Validate(path string) error {
+	_, err := parseLocation(path)
+
+	return err
+}
+
+// location identifies a file on a filesystem selected by UUID or label.
+type location struct {
+	Key   string
+	Value string
+	File  string
+}
+
+// Fetch reads a share file from a removable device. If the device is already mounted the
+// file is read from the existing mount point; otherwise the device is mounted read-only
+// on a temporary directory and unmounted afterwards.
+// Supported formats:
+//   - usb:UUID=0f3c2d1e-aaaa-4bbb-8ccc-123456789abc:/autounlock/share.txt
+//   - usb:LABEL=KEYS:/share.txt
+func (f *Fetcher) Fetch(ctx context.Context, path string) (string, error) {
+	loc, err := parseLocation(path)
+	if err != nil {
+		return "", err
+	}
+
+	runner := f.Runner
+	if runner == nil {
+		runner = execRunner{}
+	}
+
+	out, err := runner.Output(ctx, lsblkPath, "-Jpo", "NAME,FSTYPE,TYPE,UUID,LABEL,MOUNTPOINT")
+	if err != nil {
+		return "", fmt.Errorf("failed to run lsblk: %w", err)
+	}
+
+	dev, err := unraid.FindBlockDevice(out, loc.Key, loc.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to find device: %w", err)
+	}
+
+	if dev.Mountpoint != "" {
+		log.Debug().Str("device", dev.Name).Str("mountpoint", dev.Mountpoint).Msg("Device already mounted")
+
+		return readShare(dev.Mountpoint, loc.File)
+	}
+
+	return f.readFromDevice(ctx, runner, dev, loc.File)
+}
+
+func parseLocation(rawPath string) (location, error) {
+	selector, file, found := strings.Cut(strings.TrimPrefix(rawPath, "usb:"), ":")
+	if !found || file == "" {
+		return location{}, errors.New("usb location must be usb:UUID=...:/path or usb:LABEL=...:/path")
+	}
+
+	key, value, found := strings.Cut(selector, "=")
+	key = strings.ToUpper(key)
+
+	if !found || value == "" || (key != "UUID" && key != "LABEL") {
+		return location{}, fmt.Errorf("usb device must be selected by UUID= or LABEL=: %s", selector)
+	}
+
+	// Paths are relative to the filesystem root; os.Root rejects anything escaping it.
+	file = strings.TrimPrefix(path.Clean("/"+file), "/")
+	if file == "" {
+		return location{}, errors.New("usb location must name a file")
+	}
+
+	return location{Key: key, Value: value, File: file}, nil
+}
+
+func (f *Fetcher) readFromDevice(
+	ctx context.Context,
+	runner Runner,
+	dev unraid.BlockDevice,
+	file string,
+) (string, error) {
+	mountDir, err := os.MkdirTemp("", "autounlock-usb-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create mount point: %w", err)
+	}
+
+	args := []string{"-o", "ro,nosuid,nodev,noexec"}
+	if dev.Fstype != "" {
+		args = append(args, "-t", dev.Fstype)
+	}
+
+	_, err = runner.Output(ctx, mountPath, append(args, dev.Name, mountDir)...)
+	if err != nil {
+		os.Remove(mountDir)
+
+		return "", fmt.Errorf("failed to mount %s: %w", dev.Name, err)
+	}
+
+	log.Debug().Str("device", dev.Name).Str("mountpoint", mountDir).Msg("Mounted device")
+
+	defer unmount(runner, dev.Name, mountDir)
+
+	return readShare(mountDir, file)
+}
+
+// unmount runs with its own timeout so that cleanup still happens when the fetch
+// context has already expired.
+func unmount(runner Runner, device string, mountDir string) {
+	ctx, cancel := context.WithTimeout(context.Background(), unmountTimeout)
+	defer cancel()
+
+	_, err := runner.Output(ctx, umountPath, mountDir)
+	if err != nil {
+		log.Error().Err(err).Str("device", device).Str("mountpoint", mountDir).Msg("Failed to unmount device")
+
+		return
+	}
+
+	err = os.Remove(mountDir)
+	if err != nil {
+		log.Warn().Err(err).Str("mountpoint", mountDir).Msg("Failed to remove mount point")
+	}
+}
+
+func readShare(dir string, file string) (string, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to open mount point: %w", err)
+	}
+	defer root.Close()
+
+	handle, err := root.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to open share file: %w", err)
+	}
+	defer handle.Close()
+
+	data, err := io.ReadAll(io.LimitReader(handle, maxShareSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read share file: %w", err)
+	}
+
+	share := strings.TrimSpace(string(data))
+	if share == "" {
+		return "", errors.New("share file is empty")
+	}
+
+	return share, nil
+}
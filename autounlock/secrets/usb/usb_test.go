@@ -0,0 +1,268 @@
+package usb
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Testing objectives:
+// - Verify that devices are located by UUID or label and mounted read-only.
+// - Verify that the device is always unmounted and the mount point removed.
+// - Verify that already mounted devices are read in place.
+// - Ensure that invalid locations and files outside the filesystem are rejected.
+// - Ensure that warnings on stderr do not corrupt command output, but explain failures.
+
+const lsblkOutput = `{"blockdevices": [
+	{"name": "/dev/sdb", "fstype": null, "type": "disk", "uuid": null, "label": null, "mountpoint": null,
+		"children": [{"name": "/dev/sdb1", "fstype": "vfat", "type": "part",
+			"uuid": "ABCD-1234", "label": "KEYS", "mountpoint": null}]},
+	{"name": "/dev/sdc1", "fstype": "ext4", "type": "part",
+		"uuid": "0f3c2d1e-aaaa-4bbb-8ccc-123456789abc", "label": "MOUNTED", "mountpoint": "MOUNTPOINT"}
+]}`
+
+// fakeRunner simulates lsblk, mount and umount. mount populates the target directory.
+type fakeRunner struct {
+	t        *testing.T
+	lsblk    string
+	files    map[string]string
+	mountErr error
+	calls    []string
+	mountDir string
+}
+
+func (r *fakeRunner) Output(_ context.Context, name string, args ...string) ([]byte, error) {
+	r.calls = append(r.calls, name+" "+strings.Join(args, " "))
+
+	switch name {
+	case lsblkPath:
+		return []byte(r.lsblk), nil
+	case mountPath:
+		if r.mountErr != nil {
+			return nil, r.mountErr
+		}
+
+		r.mountDir = args[len(args)-1]
+
+		for file, content := range r.files {
+			target := filepath.Join(r.mountDir, file)
+
+			err := os.MkdirAll(filepath.Dir(target), 0o700)
+			if err != nil {
+				r.t.Fatalf("failed to create directory: %v", err)
+			}
+
+			err = os.WriteFile(target, []byte(content), 0o600)
+			if err != nil {
+				r.t.Fatalf("failed to write file: %v", err)
+			}
+		}
+
+		return nil, nil
+	case umountPath:
+		// Simulate the unmount by removing the files the mount provided.
+		entries, _ := os.ReadDir(args[0])
+		for _, entry := range entries {
+			os.RemoveAll(filepath.Join(args[0], entry.Name()))
+		}
+
+		return nil, nil
+	}
+
+	r.t.Fatalf("unexpected command: %s", name)
+
+	return nil, nil
+}
+
+func (r *fakeRunner) called(prefix string) bool {
+	for _, call := range r.calls {
+		if strings.HasPrefix(call, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TestFetch_MountByLabel tests mounting by label, reading the file and cleaning up.
+func TestFetch_MountByLabel(t *testing.T) {
+	runner := &fakeRunner{
+		t:     t,
+		lsblk: lsblkOutput,
+		files: map[string]string{"autounlock/share.txt": "usb-share\n"},
+	}
+
+	got, err := (&Fetcher{Runner: runner}).Fetch(context.Background(), "usb:LABEL=KEYS:/autounlock/share.txt")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if got != "usb-share" {
+		t.Errorf("share = %q, want %q", got, "usb-share")
+	}
+
+	wantMount := mountPath + " -o ro,nosuid,nodev,noexec -t vfat /dev/sdb1 " + runner.mountDir
+	if !runner.called(wantMount) {
+		t.Errorf("expected %q, got calls %v", wantMount, runner.calls)
+	}
+
+	if !runner.called(umountPath + " " + runner.mountDir) {
+		t.Errorf("device was not unmounted: %v", runner.calls)
+	}
+
+	if _, err := os.Stat(runner.mountDir); !os.IsNotExist(err) {
+		t.Errorf("mount point %s was not removed", runner.mountDir)
+	}
+}
+
+// TestFetch_MountByUUID tests that UUIDs are matched case-insensitively.
+func TestFetch_MountByUUID(t *testing.T) {
+	runner := &fakeRunner{t: t, lsblk: lsblkOutput, files: map[string]string{"share": "uuid-share"}}
+
+	got, err := (&Fetcher{Runner: runner}).Fetch(context.Background(), "usb:uuid=abcd-1234:share")
+	if err != nil || got != "uuid-share" {
+		t.Errorf("Fetch() = %q, %v; want %q", got, err, "uuid-share")
+	}
+}
+
+// TestFetch_AlreadyMounted tests that a mounted device is read without mounting it again.
+func TestFetch_AlreadyMounted(t *testing.T) {
+	mountpoint := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(mountpoint, "share.txt"), []byte("mounted-share"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write share: %v", err)
+	}
+
+	runner := &fakeRunner{t: t, lsblk: strings.Replace(lsblkOutput, "MOUNTPOINT", mountpoint, 1)}
+
+	got, err := (&Fetcher{Runner: runner}).Fetch(context.Background(), "usb:LABEL=MOUNTED:/share.txt")
+	if err != nil || got != "mounted-share" {
+		t.Errorf("Fetch() = %q, %v; want %q", got, err, "mounted-share")
+	}
+
+	if runner.called(mountPath) || runner.called(umountPath) {
+		t.Errorf("expected no mount or umount, got calls %v", runner.calls)
+	}
+}
+
+// TestFetch_UnmountsOnReadFailure tests that the device is unmounted even if the file is missing.
+func TestFetch_UnmountsOnReadFailure(t *testing.T) {
+	runner := &fakeRunner{t: t, lsblk: lsblkOutput}
+
+	_, err := (&Fetcher{Runner: runner}).Fetch(context.Background(), "usb:LABEL=KEYS:/missing.txt")
+	if err == nil {
+		t.Fatal("expected error for missing file, got none")
+	}
+
+	if !runner.called(umountPath) {
+		t.Errorf("device was not unmounted: %v", runner.calls)
+	}
+}
+
+// TestFetch_MountFailure tests that a failed mount removes the temporary directory.
+func TestFetch_MountFailure(t *testing.T) {
+	runner := &fakeRunner{t: t, lsblk: lsblkOutput, mountErr: errors.New("unknown filesystem")}
+
+	_, err := (&Fetcher{Runner: runner}).Fetch(context.Background(), "usb:LABEL=KEYS:/share.txt")
+	if err == nil {
+		t.Fatal("expected error for failed mount, got none")
+	}
+
+	if runner.called(umountPath) {
+		t.Error("umount should not run when mount failed")
+	}
+}
+
+// TestFetch_DeviceNotFound tests that a missing device is reported.
+func TestFetch_DeviceNotFound(t *testing.T) {
+	runner := &fakeRunner{t: t, lsblk: lsblkOutput}
+
+	_, err := (&Fetcher{Runner: runner}).Fetch(context.Background(), "usb:LABEL=NOPE:/share.txt")
+	if err == nil {
+		t.Error("expected error for missing device, got none")
+	}
+}
+
+// TestFetch_SymlinkEscape tests that symlinks cannot read files outside the device.
+func TestFetch_SymlinkEscape(t *testing.T) {
+	mountpoint := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret")
+
+	err := os.WriteFile(outside, []byte("outside"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	err = os.Symlink(outside, filepath.Join(mountpoint, "share.txt"))
+	if err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	runner := &fakeRunner{t: t, lsblk: strings.Replace(lsblkOutput, "MOUNTPOINT", mountpoint, 1)}
+
+	_, err = (&Fetcher{Runner: runner}).Fetch(context.Background(), "usb:LABEL=MOUNTED:/share.txt")
+	if err == nil {
+		t.Error("expected error for symlink outside the filesystem, got none")
+	}
+}
+
+// TestParseLocation tests location parsing and path normalisation.
+func TestParseLocation(t *testing.T) {
+	loc, err := parseLocation("usb:LABEL=KEYS:/../dir/./share.txt")
+	if err != nil {
+		t.Fatalf("parseLocation failed: %v", err)
+	}
+
+	if loc.Key != "LABEL" || loc.Value != "KEYS" || loc.File != "dir/share.txt" {
+		t.Errorf("unexpected location: %+v", loc)
+	}
+
+	for _, invalid := range []string{
+		"usb:LABEL=KEYS",
+		"usb:LABEL=KEYS:",
+		"usb:LABEL=KEYS:/",
+		"usb:PARTUUID=abc:/share.txt",
+		"usb:UUID=:/share.txt",
+		"usb:/dev/sdb1:/share.txt",
+	} {
+		_, err := parseLocation(invalid)
+		if err == nil {
+			t.Errorf("expected error for %q, got none", invalid)
+		}
+	}
+}
+
+// TestExecRunner_Stderr tests that stderr is left out of the output but kept for errors.
+func TestExecRunner_Stderr(t *testing.T) {
+	out, err := execRunner{}.Output(t.Context(), "/bin/sh", "-c", "echo warning >&2; echo '{}'")
+	if err != nil || string(out) != "{}\n" {
+		t.Errorf("Output() = %q, %v, want stdout only", out, err)
+	}
+
+	_, err = execRunner{}.Output(t.Context(), "/bin/sh", "-c", "echo 'mount failed' >&2; exit 32")
+	if err == nil || !strings.Contains(err.Error(), "mount failed") {
+		t.Errorf("Output() error = %v, want stderr in the message", err)
+	}
+}
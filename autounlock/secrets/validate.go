@@ -0,0 +1,192 @@
+package secrets
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"fmt"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/registry"
+)
+
+// Severity is how serious a validation finding is.
+type Severity string
+
+const (
+	// SeverityError marks a problem that keeps a location from providing its share.
+	SeverityError Severity = "error"
+	// SeverityWarning marks a likely mistake that does not stop unlocking by itself.
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a problem found while validating the configuration.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// TargetReport is the validation result of one entry of a location's fallback chain.
+type TargetReport struct {
+	Location int       `json:"location"`
+	Mirror   int       `json:"mirror"`
+	Name     string    `json:"name,omitempty"`
+	Fetcher  string    `json:"fetcher,omitempty"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// ValidationReport is the result of ValidateConfig.
+type ValidationReport struct {
+	Targets []TargetReport `json:"targets"`
+	// Usable counts the locations with a valid target that is not listed elsewhere.
+	Usable int `json:"usable"`
+	// Findings concern the configuration as a whole.
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// HasErrors reports whether any finding is an error.
+func (r ValidationReport) HasErrors() bool {
+	if hasError(r.Findings) {
+		return true
+	}
+
+	for _, target := range r.Targets {
+		if hasError(target.Findings) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type targetRef struct {
+	location int
+	mirror   int
+}
+
+// ValidateConfig checks the configured locations without contacting them. Each target must
+// be handled by a fetcher and pass its syntax check, its params must apply, and it must
+// not be listed twice. With a threshold above zero, the usable locations must be able to
+// meet it.
+func ValidateConfig(cfg config.Config, threshold int) ValidationReport {
+	var report ValidationReport
+
+	seen := make(map[string]targetRef)
+
+	for pathNum, location := range cfg.Resolved() {
+		usable := false
+
+		for mirror, path := range location.Mirrors() {
+			target, resolved := validateTarget(path, location.Params)
+			target.Location = pathNum
+			target.Mirror = mirror
+			target.Name = location.Name
+
+			// The same target would serve the same share, which only counts once.
+			first, duplicate := seen[resolved]
+			if duplicate {
+				target.Findings = append(target.Findings, Finding{
+					Severity: SeverityWarning,
+					Message: fmt.Sprintf(
+						"same target as location %d mirror %d",
+						first.location,
+						first.mirror,
+					),
+				})
+			} else {
+				seen[resolved] = targetRef{location: pathNum, mirror: mirror}
+			}
+
+			if !duplicate && !hasError(target.Findings) {
+				usable = true
+			}
+
+			report.Targets = append(report.Targets, target)
+		}
+
+		if usable {
+			report.Usable++
+		}
+	}
+
+	switch {
+	case len(cfg.Locations) == 0:
+		report.Findings = append(report.Findings, Finding{
+			Severity: SeverityError,
+			Message:  "no locations configured",
+		})
+	case threshold > 0 && report.Usable < threshold:
+		report.Findings = append(report.Findings, Finding{
+			Severity: SeverityError,
+			Message: fmt.Sprintf(
+				"%d usable locations cannot meet the threshold of %d",
+				report.Usable,
+				threshold,
+			),
+		})
+	case threshold > 0 && report.Usable == threshold:
+		report.Findings = append(report.Findings, Finding{
+			Severity: SeverityWarning,
+			Message:  "no spare location: unlocking fails if any location is unavailable",
+		})
+	}
+
+	return report
+}
+
+// validateTarget checks a single target, returning the report and the target with its
+// params applied, which identifies it among the others.
+func validateTarget(path string, params map[string]string) (TargetReport, string) {
+	var report TargetReport
+
+	fetcher, err := MatchFetcher(path)
+	if err != nil {
+		report.Findings = append(report.Findings, Finding{Severity: SeverityError, Message: err.Error()})
+
+		return report, path
+	}
+
+	report.Fetcher = fetcher.Name()
+
+	resolved, err := ApplyParams(path, params)
+	if err != nil {
+		report.Findings = append(report.Findings, Finding{Severity: SeverityError, Message: err.Error()})
+
+		return report, path
+	}
+
+	validator, ok := fetcher.(registry.Validator)
+	if ok {
+		err = validator.Validate(resolved)
+		if err != nil {
+			report.Findings = append(report.Findings, Finding{Severity: SeverityError, Message: err.Error()})
+		}
+	}
+
+	return report, resolved
+}
+
+func hasError(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
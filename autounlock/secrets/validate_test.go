@@ -0,0 +1,136 @@
+package secrets
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+)
+
+// Testing objectives:
+// - Verify that each target reports the fetcher that handles it.
+// - Ensure that syntax errors, params that do not apply and accidental catch-all matches are errors.
+// - Verify that duplicate targets are flagged and not counted as usable.
+// - Verify that the usable locations are checked against the threshold.
+
+func findingsOf(report ValidationReport, location int, mirror int) []Finding {
+	for _, target := range report.Targets {
+		if target.Location == location && target.Mirror == mirror {
+			return target.Findings
+		}
+	}
+
+	return nil
+}
+
+// TestValidateConfig tests per-target findings and the usable count.
+func TestValidateConfig(t *testing.T) {
+	cfg := config.Config{
+		Version: config.CurrentVersion,
+		Locations: []config.Location{
+			{URL: "https://nas/share", Fallback: []string{"htps://mirror/share"}},
+			{URL: "dns:share.example.com"},
+			{URL: "dns:share.example.com"},
+			{URL: "usb:LABEL=KEY", Options: config.Options{Params: map[string]string{"a": "b"}}},
+			{URL: "/boot/share.txt"},
+		},
+	}
+
+	report := ValidateConfig(cfg, 0)
+
+	if len(report.Targets) != 6 {
+		t.Fatalf("got %d targets, want 6", len(report.Targets))
+	}
+
+	if report.Targets[0].Fetcher != "http" || report.Targets[1].Fetcher != "rclone" {
+		t.Errorf("unexpected fetchers: %q, %q", report.Targets[0].Fetcher, report.Targets[1].Fetcher)
+	}
+
+	testCases := []struct {
+		location int
+		mirror   int
+		severity Severity
+		contains string
+	}{
+		{0, 1, SeverityError, "unknown location type"},
+		{2, 0, SeverityWarning, "same target as location 1 mirror 0"},
+		{3, 0, SeverityError, "does not accept params"},
+	}
+
+	for _, tc := range testCases {
+		findings := findingsOf(report, tc.location, tc.mirror)
+		if len(findings) != 1 || findings[0].Severity != tc.severity ||
+			!strings.Contains(findings[0].Message, tc.contains) {
+			t.Errorf("location %d mirror %d: findings %+v, want %s %q",
+				tc.location, tc.mirror, findings, tc.severity, tc.contains)
+		}
+	}
+
+	for _, ok := range [][2]int{{0, 0}, {1, 0}, {4, 0}} {
+		if findings := findingsOf(report, ok[0], ok[1]); len(findings) != 0 {
+			t.Errorf("location %d mirror %d: unexpected findings %+v", ok[0], ok[1], findings)
+		}
+	}
+
+	if report.Usable != 3 {
+		t.Errorf("Usable = %d, want 3", report.Usable)
+	}
+
+	if !report.HasErrors() {
+		t.Error("expected errors")
+	}
+}
+
+// TestValidateConfig_Threshold tests checking the usable locations against the threshold.
+func TestValidateConfig_Threshold(t *testing.T) {
+	cfg := config.Config{
+		Version:   config.CurrentVersion,
+		Locations: LocationsFromPaths([]string{"dns:a.example.com", "dns:b.example.com"}),
+	}
+
+	testCases := []struct {
+		threshold int
+		severity  Severity
+	}{
+		{0, ""},
+		{1, ""},
+		{2, SeverityWarning},
+		{3, SeverityError},
+	}
+
+	for _, tc := range testCases {
+		report := ValidateConfig(cfg, tc.threshold)
+
+		var severity Severity
+		if len(report.Findings) > 0 {
+			severity = report.Findings[0].Severity
+		}
+
+		if severity != tc.severity {
+			t.Errorf("threshold %d: findings %+v, want %q", tc.threshold, report.Findings, tc.severity)
+		}
+	}
+
+	report := ValidateConfig(config.Config{Version: config.CurrentVersion}, 0)
+	if !report.HasErrors() {
+		t.Error("expected an error without locations")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets/mdns"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/server"
+	"github.com/rs/zerolog/log"
+)
+
+// Serve hosts shares from a local directory so that other servers can use this one
+// as an https:// location. Runs until ctx is cancelled.
+func (a *AutoUnlock) Serve(ctx context.Context) error {
+	cmd := a.args.Serve
+
+	var accessLog io.Writer
+
+	if cmd.AccessLog != "" {
+		file, err := os.OpenFile(
+			cmd.AccessLog,
+			os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+			constants.StateFileMode,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to open access log: %w", err)
+		}
+		defer file.Close()
+
+		accessLog = file
+	}
+
+	srv, err := server.New(server.Config{
+		Listen:          cmd.Listen,
+		Dir:             cmd.Dir,
+		CertFile:        cmd.Cert,
+		KeyFile:         cmd.Key,
+		ClientCAFile:    cmd.ClientCA,
+		TokensFile:      cmd.Tokens,
+		TrustedKeysFile: cmd.TrustedKeys,
+		Allow:           cmd.Allow,
+		RateLimit:       cmd.RateLimit,
+		AccessLog:       accessLog,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to configure share server: %w", err)
+	}
+
+	if cmd.MDNS {
+		advert, err := advertise(cmd.Listen, cmd.MDNSName)
+		if err != nil {
+			return err
+		}
+		defer advert.Close()
+	}
+
+	err = srv.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to serve shares: %w", err)
+	}
+
+	return nil
+}
+
+// advertise announces the server under the port it listens on.
+func advertise(listen string, name string) (io.Closer, error) {
+	_, portStr, err := net.SplitHostPort(listen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address: %w", err)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen port: %w", err)
+	}
+
+	advert, err := mdns.Advertise(name, port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to advertise share server: %w", err)
+	}
+
+	log.Info().Str("service", mdns.ServiceType).Int("port", port).Msg("Advertising share server")
+
+	return advert, nil
+}
@@ -0,0 +1,140 @@
+package server
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	certificateValidity = 10 * 365 * 24 * time.Hour
+	serialNumberBits    = 128
+)
+
+// ensureCertificate loads the server certificate, generating a long-lived self-signed
+// certificate if neither file exists. Clients are expected to pin it with tls-pin.
+func ensureCertificate(certFile string, keyFile string) (tls.Certificate, error) {
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+
+	if errors.Is(certErr, os.ErrNotExist) && errors.Is(keyErr, os.ErrNotExist) {
+		err := generateCertificate(certFile, keyFile)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+
+		log.Info().Str("cert", certFile).Msg("Generated self-signed server certificate")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+func generateCertificate(certFile string, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate server key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialNumberBits))
+	if err != nil {
+		return fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hostname},
+		DNSNames:     []string{hostname, "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(certificateValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("failed to create server certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal server key: %w", err)
+	}
+
+	for _, dir := range []string{filepath.Dir(certFile), filepath.Dir(keyFile)} {
+		err = os.MkdirAll(dir, constants.StateDirMode)
+		if err != nil {
+			return fmt.Errorf("failed to create certificate directory: %w", err)
+		}
+	}
+
+	err = os.WriteFile(
+		keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		constants.StateFileMode,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write server key: %w", err)
+	}
+
+	err = os.WriteFile(
+		certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		constants.StateFileMode,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write server certificate: %w", err)
+	}
+
+	return nil
+}
+
+// spkiPin returns the pin value accepted by the HTTP fetcher's tls-pin parameter.
+func spkiPin(cert *x509.Certificate) string {
+	if cert == nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,424 @@
+package server
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/httpsig"
+	fetcher "github.com/dkaser/unraid-auto-unlock/autounlock/secrets/http"
+	"github.com/spf13/afero"
+)
+
+// Testing objectives:
+// - Verify that shares are served to clients presenting a valid token or signature.
+// - Ensure that unauthenticated, disallowed and rate limited clients are rejected.
+// - Ensure that share names cannot escape the share directory.
+// - Verify that the HTTP fetcher can retrieve shares using a pinned self-signed certificate.
+
+const testToken = "s3cret-token"
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// newTestServer creates a share directory containing box1 and a server using it.
+func newTestServer(t *testing.T, modify func(config *Config)) *Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	shares := filepath.Join(dir, "shares")
+
+	err := os.Mkdir(shares, 0o700)
+	if err != nil {
+		t.Fatalf("failed to create share directory: %v", err)
+	}
+
+	writeFile(t, filepath.Join(shares, "box1"), "share-one\n")
+	writeFile(t, filepath.Join(dir, "secret"), "outside")
+	writeFile(t, filepath.Join(dir, "tokens"), "# comment\n\n"+testToken+"\n")
+
+	config := Config{
+		Listen:     "127.0.0.1:0",
+		Dir:        shares,
+		CertFile:   filepath.Join(dir, "server.crt"),
+		KeyFile:    filepath.Join(dir, "server.key"),
+		TokensFile: filepath.Join(dir, "tokens"),
+	}
+
+	if modify != nil {
+		modify(&config)
+	}
+
+	srv, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	t.Cleanup(func() { srv.root.Close() })
+
+	return srv
+}
+
+func request(srv *Server, target string, setup func(req *http.Request)) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if setup != nil {
+		setup(req)
+	}
+
+	recorder := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+func bearer(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+testToken)
+}
+
+// TestNew_RequiresAuthentication tests that a server without any authentication method is refused.
+func TestNew_RequiresAuthentication(t *testing.T) {
+	_, err := New(Config{Dir: t.TempDir()})
+	if err == nil {
+		t.Error("expected error when no authentication is configured, got none")
+	}
+}
+
+// TestServe_Token tests token authentication via bearer and basic auth.
+func TestServe_Token(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	testCases := []struct {
+		name       string
+		setup      func(req *http.Request)
+		wantStatus int
+	}{
+		{"bearer", bearer, http.StatusOK},
+		{"basic", func(req *http.Request) { req.SetBasicAuth("unraid", testToken) }, http.StatusOK},
+		{"missing", nil, http.StatusUnauthorized},
+		{"wrong", func(req *http.Request) { req.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := request(srv, "https://share.local/box1", tc.setup)
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, tc.wantStatus)
+			}
+
+			if tc.wantStatus == http.StatusOK && resp.Body.String() != "share-one" {
+				t.Errorf("body = %q, want %q", resp.Body.String(), "share-one")
+			}
+		})
+	}
+}
+
+// TestServe_SignedRequest tests that signatures from trusted device keys are required.
+func TestServe_SignedRequest(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	keysFile := filepath.Join(t.TempDir(), "trusted")
+	writeFile(t, keysFile, httpsig.KeyID(pub)+"\n")
+
+	srv := newTestServer(t, func(config *Config) {
+		config.TokensFile = ""
+		config.TrustedKeysFile = keysFile
+	})
+
+	sign := func(key ed25519.PrivateKey) func(req *http.Request) {
+		return func(req *http.Request) {
+			err := httpsig.Sign(req, key, "", time.Now())
+			if err != nil {
+				t.Fatalf("Sign failed: %v", err)
+			}
+		}
+	}
+
+	if resp := request(srv, "https://share.local/box1", sign(key)); resp.Code != http.StatusOK {
+		t.Errorf("trusted key status = %d, want %d", resp.Code, http.StatusOK)
+	}
+
+	if resp := request(srv, "https://share.local/box1", sign(otherKey)); resp.Code != http.StatusUnauthorized {
+		t.Errorf("untrusted key status = %d, want %d", resp.Code, http.StatusUnauthorized)
+	}
+
+	if resp := request(srv, "https://share.local/box1", nil); resp.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned status = %d, want %d", resp.Code, http.StatusUnauthorized)
+	}
+}
+
+// TestServe_ClientCertificate tests that a verified client certificate is required when a client CA is set.
+func TestServe_ClientCertificate(t *testing.T) {
+	srv := newTestServer(t, func(config *Config) {
+		config.TokensFile = ""
+		config.ClientCAFile = "/unused/ca.pem"
+	})
+
+	if resp := request(srv, "https://share.local/box1", nil); resp.Code != http.StatusUnauthorized {
+		t.Errorf("no certificate status = %d, want %d", resp.Code, http.StatusUnauthorized)
+	}
+
+	resp := request(srv, "https://share.local/box1", func(req *http.Request) {
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "tower"}}},
+		}
+	})
+	if resp.Code != http.StatusOK {
+		t.Errorf("certificate status = %d, want %d", resp.Code, http.StatusOK)
+	}
+}
+
+// TestServe_ShareNames tests that only files directly inside the share directory are served.
+func TestServe_ShareNames(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	for _, target := range []string{
+		"https://share.local/missing",
+		"https://share.local/../secret",
+		"https://share.local/%2e%2e%2fsecret",
+		"https://share.local/.hidden",
+		"https://share.local/",
+	} {
+		resp := request(srv, target, bearer)
+		if resp.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", target, resp.Code, http.StatusNotFound)
+		}
+	}
+}
+
+// TestServe_MethodNotAllowed tests that only GET requests are served.
+func TestServe_MethodNotAllowed(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	resp := request(srv, "https://share.local/box1", func(req *http.Request) {
+		req.Method = http.MethodPut
+		bearer(req)
+	})
+	if resp.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", resp.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+// TestServe_AllowList tests that clients outside the allowlist are refused before authentication.
+func TestServe_AllowList(t *testing.T) {
+	srv := newTestServer(t, func(config *Config) {
+		config.Allow = []string{"10.0.0.0/8", "192.168.1.5"}
+	})
+
+	testCases := []struct {
+		remote     string
+		wantStatus int
+	}{
+		{"10.1.2.3:5000", http.StatusOK},
+		{"192.168.1.5:5000", http.StatusOK},
+		{"192.168.1.6:5000", http.StatusForbidden},
+		{"[::1]:5000", http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		resp := request(srv, "https://share.local/box1", func(req *http.Request) {
+			req.RemoteAddr = tc.remote
+			req.Header.Set("X-Forwarded-For", "10.0.0.1")
+			bearer(req)
+		})
+		if resp.Code != tc.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tc.remote, resp.Code, tc.wantStatus)
+		}
+	}
+}
+
+// TestServe_RateLimit tests that clients exceeding the rate limit are refused.
+func TestServe_RateLimit(t *testing.T) {
+	srv := newTestServer(t, func(config *Config) {
+		config.RateLimit = 2
+	})
+
+	for i := range 2 {
+		if resp := request(srv, "https://share.local/box1", bearer); resp.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i, resp.Code, http.StatusOK)
+		}
+	}
+
+	if resp := request(srv, "https://share.local/box1", bearer); resp.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", resp.Code, http.StatusTooManyRequests)
+	}
+
+	other := request(srv, "https://share.local/box1", func(req *http.Request) {
+		req.RemoteAddr = "192.0.2.99:1234"
+		bearer(req)
+	})
+	if other.Code != http.StatusOK {
+		t.Errorf("other client status = %d, want %d", other.Code, http.StatusOK)
+	}
+}
+
+// TestRateLimiter_Window tests that the limit resets once the window has passed.
+func TestRateLimiter_Window(t *testing.T) {
+	limiter := newRateLimiter(1, time.Minute)
+	now := time.Now()
+
+	if !limiter.allow("a", now) || limiter.allow("a", now.Add(time.Second)) {
+		t.Fatal("expected only the first request in the window to be allowed")
+	}
+
+	if !limiter.allow("a", now.Add(time.Minute)) {
+		t.Error("expected request in the next window to be allowed")
+	}
+}
+
+// TestServe_AccessLog tests that requests are written to the access log.
+func TestServe_AccessLog(t *testing.T) {
+	var logged strings.Builder
+
+	srv := newTestServer(t, func(config *Config) {
+		config.AccessLog = &logged
+	})
+
+	request(srv, "https://share.local/box1", bearer)
+
+	for _, want := range []string{`"path":"/box1"`, `"status":200`, `"identity":"token"`} {
+		if !strings.Contains(logged.String(), want) {
+			t.Errorf("access log %q does not contain %s", logged.String(), want)
+		}
+	}
+}
+
+// TestParseAllowList_Invalid tests that malformed allowlist entries are rejected.
+func TestParseAllowList_Invalid(t *testing.T) {
+	for _, entry := range []string{"not-an-ip", "10.0.0.0/99"} {
+		_, err := parseAllowList([]string{entry})
+		if err == nil {
+			t.Errorf("expected error for %q, got none", entry)
+		}
+	}
+}
+
+// TestEnsureCertificate_Reuse tests that the generated certificate is reused on restart.
+func TestEnsureCertificate_Reuse(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	_, first, err := srv.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig failed: %v", err)
+	}
+
+	_, second, err := srv.TLSConfig()
+	if err != nil {
+		t.Fatalf("second TLSConfig failed: %v", err)
+	}
+
+	if first == "" || first != second {
+		t.Errorf("pins differ between runs: %q and %q", first, second)
+	}
+}
+
+// TestServe_FetcherCompatibility tests that the HTTP fetcher retrieves shares from the server
+// using a pinned self-signed certificate, a token and a signed request.
+func TestServe_FetcherCompatibility(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "device.key")
+
+	pub, _, err := httpsig.EnsureKeyFile(afero.NewOsFs(), keyPath)
+	if err != nil {
+		t.Fatalf("EnsureKeyFile failed: %v", err)
+	}
+
+	keysFile := filepath.Join(dir, "trusted")
+	writeFile(t, keysFile, httpsig.KeyID(pub)+"\n")
+
+	srv := newTestServer(t, func(config *Config) {
+		config.TrustedKeysFile = keysFile
+	})
+
+	tlsConfig, pin, err := srv.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig failed: %v", err)
+	}
+
+	httpServer := httptest.NewUnstartedServer(srv.Handler())
+	httpServer.TLS = tlsConfig
+	httpServer.StartTLS()
+	defer httpServer.Close()
+
+	base, _ := url.Parse(httpServer.URL)
+	target := "https://unraid:" + testToken + "@" + base.Host + "/box1" +
+		"?tls-pin=" + url.QueryEscape(pin) + "&sig-key=" + url.QueryEscape(keyPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	share, err := (&fetcher.Fetcher{}).Fetch(ctx, target)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if share != "share-one" {
+		t.Errorf("share = %q, want %q", share, "share-one")
+	}
+
+	_, err = (&fetcher.Fetcher{}).Fetch(ctx, strings.Replace(target, testToken, "wrong", 1))
+	if err == nil {
+		t.Error("expected error for wrong token, got none")
+	}
+}
+
+// TestRun_Shutdown tests that Run returns cleanly when the context is cancelled.
+func TestRun_Shutdown(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() { done <- srv.Run(ctx) }()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
@@ -21,24 +21,81 @@ package main
 import (
 	"encoding/base64"
 	"fmt"
+	"math"
 
+	"github.com/dkaser/unraid-auto-unlock/autounlock/httpsig"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/recovery"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
 	"github.com/rs/zerolog/log"
 )
 
+// pendingSuffix is appended to the recovery file name while setup is not yet complete.
+const pendingSuffix = ".new"
+
+// SetupResult is the result of setup.
+type SetupResult struct {
+	Threshold uint16 `json:"threshold"`
+	// Shares are the base64 encoded shares to store, excluding the recovery share.
+	Shares          []string `json:"shares"`
+	DevicePublicKey string   `json:"devicePublicKey"`
+	RecoveryFile    string   `json:"recoveryFile,omitempty"`
+}
+
 // Setup configures the auto-unlock system.
+//
+//nolint:funlen // Setup writes each piece in turn
 func (a *AutoUnlock) Setup() error {
+	// The recovery share is an extra share on top of the ones handed out, which must still
+	// fit the share count.
+	if a.args.Setup.Recovery && a.args.Setup.Shares == math.MaxUint16 {
+		return fmt.Errorf("at most %d shares can be used with a recovery share", math.MaxUint16-1)
+	}
+
 	err := a.unraid.TestKeyfile(a.args.KeyFile)
 	if err != nil {
-		return fmt.Errorf("keyfile test failed: %w", err)
+		return withCode(CodeKeyfileInvalid, fmt.Errorf("keyfile test failed: %w", err))
 	}
 
 	log.Info().Msg("Keyfile test succeeded")
 
-	secret, err := a.secrets.CreateSecret(a.args.Setup.Threshold, a.args.Setup.Shares)
+	a.migrateLegacyConfig()
+
+	var passphrase []byte
+
+	totalShares := a.args.Setup.Shares
+	if a.args.Setup.Recovery {
+		passphrase, err = readPassphrase(true)
+		if err != nil {
+			return err
+		}
+
+		if len([]rune(string(passphrase))) < recovery.MinPassphraseLength {
+			return fmt.Errorf(
+				"recovery passphrase must be at least %d characters",
+				recovery.MinPassphraseLength,
+			)
+		}
+
+		totalShares++
+	}
+
+	secret, err := a.secrets.CreateSecret(a.args.Setup.Threshold, totalShares)
 	if err != nil {
 		return fmt.Errorf("failed to create secret: %w", err)
 	}
 
+	// The recovery share is sealed first, so that a failure leaves the previous setup,
+	// and its recovery file, intact.
+	pendingRecovery, err := a.sealRecovery(secret, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if pendingRecovery != "" {
+		// Only left over when a later step fails.
+		defer a.fs.Remove(pendingRecovery) //nolint:errcheck // Best effort cleanup
+	}
+
 	err = a.state.WriteStateToFile(
 		secret.VerificationKey,
 		secret.SigningKey,
@@ -69,17 +126,94 @@ func (a *AutoUnlock) Setup() error {
 		Str("encryptedfile", a.args.EncryptedFile).
 		Msg("Encrypted file")
 
+	// The device key is kept across setups so that share servers verifying
+	// request signatures do not need to be reconfigured.
+	devicePub, created, err := httpsig.EnsureKeyFile(a.fs, a.args.DeviceKey)
+	if err != nil {
+		return fmt.Errorf("failed to prepare device key: %w", err)
+	}
+
+	log.Info().
+		Str("devicekey", a.args.DeviceKey).
+		Bool("created", created).
+		Msg("Device signing key ready")
+
+	err = a.setupRecovery(pendingRecovery)
+	if err != nil {
+		return err
+	}
+
+	result := SetupResult{
+		Threshold:       a.args.Setup.Threshold,
+		DevicePublicKey: httpsig.KeyID(devicePub),
+	}
+
+	for _, share := range secret.Shares[:a.args.Setup.Shares] {
+		result.Shares = append(result.Shares, base64.StdEncoding.EncodeToString(share))
+	}
+
+	if a.args.Setup.Recovery {
+		result.RecoveryFile = a.args.RecoveryFile
+	}
+
+	return a.emit(result, func() error {
+		printSetupResult(result)
+
+		return nil
+	})
+}
+
+func printSetupResult(result SetupResult) {
 	// Output the threshold and shares
-	fmt.Printf("Total Shares: %d\n", a.args.Setup.Shares)
-	fmt.Printf("Unlock Threshold: %d\n\n", a.args.Setup.Threshold)
+	fmt.Printf("Total Shares: %d\n", len(result.Shares))
+	fmt.Printf("Unlock Threshold: %d\n\n", result.Threshold)
 
 	fmt.Println("Share values (base64 encoded):")
 
 	// Output each share as base64, one per line
-	for _, share := range secret.Shares {
-		shareB64 := base64.StdEncoding.EncodeToString(share)
-		fmt.Println(shareB64)
+	for _, share := range result.Shares {
+		fmt.Println(share)
+	}
+
+	fmt.Printf("\nDevice public key: %s\n", result.DevicePublicKey)
+
+	if result.RecoveryFile != "" {
+		fmt.Printf("Recovery share: %s\n", result.RecoveryFile)
+	}
+}
+
+// sealRecovery seals the extra share under the passphrase next to the recovery file,
+// returning the path written, or "" without a recovery share.
+func (a *AutoUnlock) sealRecovery(secret secrets.SharedSecret, passphrase []byte) (string, error) {
+	if !a.args.Setup.Recovery {
+		return "", nil
 	}
 
+	share := base64.StdEncoding.EncodeToString(secret.Shares[a.args.Setup.Shares])
+	pending := a.args.RecoveryFile + pendingSuffix
+
+	err := a.recovery.Write(pending, share, passphrase, recovery.DefaultParams)
+	if err != nil {
+		return "", fmt.Errorf("failed to write recovery share: %w", err)
+	}
+
+	return pending, nil
+}
+
+// setupRecovery moves the sealed recovery share into place once the new secret is in
+// use, or removes the recovery file left by a previous setup since its share no longer
+// matches the new secret.
+func (a *AutoUnlock) setupRecovery(pending string) error {
+	if pending == "" {
+		return a.safeRemoveFile(a.args.RecoveryFile)
+	}
+
+	err := a.fs.Rename(pending, a.args.RecoveryFile)
+	if err != nil {
+		return fmt.Errorf("failed to write recovery share: %w", err)
+	}
+
+	log.Info().Str("recoveryfile", a.args.RecoveryFile).Msg("Wrote recovery share")
+
 	return nil
 }
@@ -0,0 +1,98 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/recovery"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
+	"github.com/spf13/afero"
+)
+
+// Testing objectives:
+// - Ensure that a share count with no room for the recovery share is rejected up front.
+// - Verify that the recovery share only replaces the previous one once moved into place.
+
+// TestSetup_RecoveryShareCount tests rejecting --shares 65535 with --recovery.
+func TestSetup_RecoveryShareCount(t *testing.T) {
+	// No services are set, so any step past the check would panic.
+	autoUnlock := &AutoUnlock{
+		args: CmdArgs{Setup: &SetupCmd{Threshold: 2, Shares: math.MaxUint16, Recovery: true}},
+	}
+
+	err := autoUnlock.Setup()
+	if err == nil {
+		t.Error("Expected an error for 65535 shares with a recovery share")
+	}
+}
+
+// TestSetupRecovery tests sealing the recovery share before moving it into place.
+func TestSetupRecovery(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	recoveryFile := "/boot/config/plugins/auto-unlock/recovery.json"
+
+	err := afero.WriteFile(fs, recoveryFile, []byte("previous"), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to create recovery file: %v", err)
+	}
+
+	svc := secrets.NewService(fs, "", false, false)
+
+	secret, err := svc.CreateSecret(2, 3)
+	if err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+
+	autoUnlock := &AutoUnlock{
+		fs: fs,
+		args: CmdArgs{
+			RecoveryFile: recoveryFile,
+			Setup:        &SetupCmd{Threshold: 2, Shares: 2, Recovery: true},
+		},
+		recovery: recovery.NewService(fs),
+	}
+
+	pending, err := autoUnlock.sealRecovery(secret, []byte("correct horse battery staple"))
+	if err != nil {
+		t.Fatalf("sealRecovery failed: %v", err)
+	}
+
+	// Until setup completes, the previous recovery file is left alone.
+	previous, _ := afero.ReadFile(fs, recoveryFile)
+	if string(previous) != "previous" {
+		t.Errorf("recovery file replaced early: %q", previous)
+	}
+
+	err = autoUnlock.setupRecovery(pending)
+	if err != nil {
+		t.Fatalf("setupRecovery failed: %v", err)
+	}
+
+	exists, _ := afero.Exists(fs, pending)
+	if exists {
+		t.Error("pending recovery file left behind")
+	}
+
+	share, err := autoUnlock.recovery.Read(recoveryFile, []byte("correct horse battery staple"))
+	if err != nil || share == "" {
+		t.Errorf("recovery share not readable: %v", err)
+	}
+}
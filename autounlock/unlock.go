@@ -19,37 +19,70 @@ package main
 */
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/bytemare/secret-sharing/keys"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/state"
 	"github.com/rs/zerolog/log"
 )
 
+// UnlockResult is the result of unlock.
+type UnlockResult struct {
+	Test bool `json:"test"`
+	// Shares counts the shares combined, including the recovery share.
+	Shares    int  `json:"shares"`
+	Threshold int  `json:"threshold"`
+	Decrypted bool `json:"decrypted"`
+	// ArrayStarted is set once the array is started, which never happens in test mode.
+	ArrayStarted bool `json:"arrayStarted"`
+}
+
 //nolint:cyclop,funlen // Unlock decrypts the keyfile and starts the array.
 func (a *AutoUnlock) Unlock() error {
+	result := &UnlockResult{Test: a.args.Unlock.Test}
+
+	// Recorded up front so that the progress made is reported when unlocking fails.
+	// Text output is the log.
+	err := a.emit(result, nil)
+	if err != nil {
+		return err
+	}
+
 	if !a.args.Unlock.Test {
 		started := a.unraid.VerifyArrayStatus("Started")
 		if started {
-			return errors.New("array is already started, aborting unlock")
+			return withCode(
+				CodeArrayStarted,
+				errors.New("array is already started, aborting unlock"),
+			)
 		}
 
 		err := a.unraid.WaitForArrayStatus("Stopped", constants.ArrayStatusTimeout)
 		if err != nil {
-			return fmt.Errorf("failed to verify array stopped: %w", err)
+			return withCode(
+				CodeArrayNotStopped,
+				fmt.Errorf("failed to verify array stopped: %w", err),
+			)
 		}
 	}
 
-	state, err := a.state.ReadStateFromFile(a.args.State)
+	state, err := a.readState()
 	if err != nil {
-		return fmt.Errorf("failed to read state from file: %w", err)
+		return err
 	}
 
+	result.Threshold = int(state.Threshold)
+
 	defer a.RemoveKeyfile()
 
-	secret, err := a.retrieveSecret(state)
+	secret, shares, err := a.retrieveSecret(state)
+	result.Shares = shares
+
 	if err != nil {
 		return fmt.Errorf("failed to retrieve secret: %w", err)
 	}
@@ -61,9 +94,11 @@ func (a *AutoUnlock) Unlock() error {
 		state.Nonce,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to decrypt file: %w", err)
+		return withCode(CodeDecryptFailed, fmt.Errorf("failed to decrypt file: %w", err))
 	}
 
+	result.Decrypted = true
+
 	log.Info().
 		Str("encryptedfile", a.args.EncryptedFile).
 		Str("keyfile", a.args.KeyFile).
@@ -72,7 +107,7 @@ func (a *AutoUnlock) Unlock() error {
 	if a.args.Unlock.Test {
 		err := a.unraid.TestKeyfile(a.args.KeyFile)
 		if err != nil {
-			return fmt.Errorf("keyfile test failed: %w", err)
+			return withCode(CodeKeyfileInvalid, fmt.Errorf("keyfile test failed: %w", err))
 		}
 
 		log.Info().Msg("Keyfile test succeeded")
@@ -90,45 +125,111 @@ func (a *AutoUnlock) Unlock() error {
 		if started {
 			log.Info().Msg("Array started successfully on its own")
 
+			result.ArrayStarted = true
+
 			return nil
 		}
 
 		err = a.unraid.StartArray()
 		if err != nil {
-			return fmt.Errorf("failed to start array: %w", err)
+			return withCode(CodeStartFailed, fmt.Errorf("failed to start array: %w", err))
 		}
 	}
 
 	err = a.unraid.WaitForArrayStatus("Started", constants.ArrayTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to verify array started: %w", err)
+		return withCode(CodeStartFailed, fmt.Errorf("failed to verify array started: %w", err))
 	}
 
+	result.ArrayStarted = true
+
 	return nil
 }
 
-func (a *AutoUnlock) retrieveSecret(appState state.State) ([]byte, error) {
-	sharePaths, err := a.secrets.ReadPathsFromFile(a.args.Config)
+// retrieveSecret collects and combines the shares, returning the secret and the number
+// of shares collected.
+func (a *AutoUnlock) retrieveSecret(appState state.State) ([]byte, int, error) {
+	cfg, err := a.loadConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read paths from config file: %w", err)
+		return nil, 0, err
+	}
+
+	var seed []*keys.KeyShare
+
+	if a.args.Unlock.Passphrase {
+		share, err := a.recoveryShare(appState)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		seed = append(seed, share)
+	}
+
+	ctx := context.Background()
+
+	if a.args.Unlock.Deadline > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(a.args.Unlock.Deadline)*time.Second)
+		defer cancel()
 	}
 
 	shares, err := a.secrets.GetShares(
-		sharePaths,
+		ctx,
+		cfg.Resolved(),
+		cfg.MaxConcurrency,
 		appState,
 		a.args.Unlock.RetryDelay,
 		a.args.Unlock.ServerTimeout,
 		a.args.Unlock.Test,
 		a.unraid,
+		seed,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get shares: %w", err)
+		// Running out of time counts as not finding enough shares.
+		code := CodeInsufficientShares
+		if errors.Is(err, secrets.ErrArrayStarted) {
+			code = CodeArrayStarted
+		}
+
+		return nil, len(seed), withCode(code, fmt.Errorf("failed to get shares: %w", err))
 	}
 
 	secret, err := a.secrets.CombineSecret(shares)
 	if err != nil {
-		return nil, fmt.Errorf("failed to combine secret: %w", err)
+		return nil, len(shares), withCode(
+			CodeDecryptFailed,
+			fmt.Errorf("failed to combine secret: %w", err),
+		)
 	}
 
-	return secret, nil
+	return secret, len(shares), nil
+}
+
+// recoveryShare decrypts the recovery share with the passphrase read from stdin.
+func (a *AutoUnlock) recoveryShare(appState state.State) (*keys.KeyShare, error) {
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+
+	shareStr, err := a.recovery.Read(a.args.RecoveryFile, passphrase)
+	if err != nil {
+		return nil, withCode(
+			CodeRecoveryFailed,
+			fmt.Errorf("failed to open recovery share: %w", err),
+		)
+	}
+
+	share, err := a.secrets.GetShare(shareStr, appState.SigningKey)
+	if err != nil {
+		return nil, withCode(
+			CodeRecoveryFailed,
+			fmt.Errorf("failed to verify recovery share: %w", err),
+		)
+	}
+
+	log.Info().Str("recoveryfile", a.args.RecoveryFile).Msg("Using recovery share")
+
+	return share, nil
 }
@@ -50,10 +50,13 @@ func NewService(fs afero.Fs) *Service {
 
 // BlockDevice represents a single block device from lsblk output.
 type BlockDevice struct {
-	Name     string        `json:"name"`
-	Fstype   string        `json:"fstype"`
-	Type     string        `json:"type"`
-	Children []BlockDevice `json:"children"`
+	Name       string        `json:"name"`
+	Fstype     string        `json:"fstype"`
+	Type       string        `json:"type"`
+	UUID       string        `json:"uuid"`
+	Label      string        `json:"label"`
+	Mountpoint string        `json:"mountpoint"`
+	Children   []BlockDevice `json:"children"`
 }
 
 // BlockDevices represents the top-level lsblk JSON output.
@@ -110,6 +113,53 @@ func ParseLUKSDevices(data []byte) ([]string, error) {
 	return results, nil
 }
 
+// FindBlockDevice parses lsblk JSON output (including the UUID and LABEL columns) and
+// returns the first device whose filesystem UUID (case-insensitive) or label matches.
+// key is either "UUID" or "LABEL".
+func FindBlockDevice(data []byte, key string, value string) (BlockDevice, error) {
+	var lsblk BlockDevices
+
+	err := json.Unmarshal(data, &lsblk)
+	if err != nil {
+		return BlockDevice{}, fmt.Errorf("failed to parse lsblk output: %w", err)
+	}
+
+	matches := func(dev BlockDevice) bool {
+		switch strings.ToUpper(key) {
+		case "UUID":
+			return strings.EqualFold(dev.UUID, value)
+		case "LABEL":
+			return dev.Label == value
+		default:
+			return false
+		}
+	}
+
+	var walk func(devices []BlockDevice) (BlockDevice, bool)
+
+	walk = func(devices []BlockDevice) (BlockDevice, bool) {
+		for _, dev := range devices {
+			if matches(dev) {
+				return dev, true
+			}
+
+			found, ok := walk(dev.Children)
+			if ok {
+				return found, true
+			}
+		}
+
+		return BlockDevice{}, false
+	}
+
+	dev, ok := walk(lsblk.BlockDevices)
+	if !ok {
+		return BlockDevice{}, fmt.Errorf("no block device with %s=%s", strings.ToUpper(key), value)
+	}
+
+	return dev, nil
+}
+
 // GetLUKSDevices returns the list of block devices that should be tested as LUKS targets.
 func (s *Service) GetLUKSDevices() ([]string, error) {
 	out, err := exec.Command("/bin/lsblk", "-Jpo", "NAME,FSTYPE,TYPE").Output()
@@ -120,6 +170,27 @@ func (s *Service) GetLUKSDevices() ([]string, error) {
 	return ParseLUKSDevices(out)
 }
 
+// notifyScript raises notifications through the Unraid web GUI.
+const notifyScript = "/usr/local/emhttp/webGui/scripts/notify"
+
+// Notify raises an Unraid notification. Importance is normal, warning or alert.
+func (s *Service) Notify(subject string, description string, importance string) error {
+	cmd := exec.Command( // #nosec G204
+		notifyScript,
+		"-e", "Auto Unlock",
+		"-s", subject,
+		"-d", description,
+		"-i", importance,
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to raise notification: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
 // IsUnraid checks if the system is running Unraid.
 func (s *Service) IsUnraid() bool {
 	_, err := s.fs.Stat("/etc/unraid-version")
@@ -601,3 +601,48 @@ func TestParseLUKSDevices_MalformedJSON(t *testing.T) {
 		t.Error("ParseLUKSDevices should return an error for malformed JSON")
 	}
 }
+
+const lsblkUSBSample = `{"blockdevices": [
+	{"name": "/dev/sda", "fstype": null, "type": "disk", "uuid": null, "label": null, "mountpoint": null,
+		"children": [{"name": "/dev/sda1", "fstype": "vfat", "type": "part", "uuid": "1234-ABCD",
+			"label": "UNRAID", "mountpoint": "/boot"}]},
+	{"name": "/dev/sdb", "fstype": null, "type": "disk", "uuid": null, "label": null, "mountpoint": null,
+		"children": [{"name": "/dev/sdb1", "fstype": "ext4", "type": "part",
+			"uuid": "0f3c2d1e-aaaa-4bbb-8ccc-123456789abc", "label": "KEYS", "mountpoint": null}]}
+]}`
+
+func TestFindBlockDevice_ByUUID(t *testing.T) {
+	dev, err := FindBlockDevice([]byte(lsblkUSBSample), "uuid", "0F3C2D1E-AAAA-4BBB-8CCC-123456789ABC")
+	if err != nil {
+		t.Fatalf("FindBlockDevice returned unexpected error: %v", err)
+	}
+
+	if dev.Name != "/dev/sdb1" || dev.Fstype != "ext4" || dev.Mountpoint != "" {
+		t.Errorf("unexpected device: %+v", dev)
+	}
+}
+
+func TestFindBlockDevice_ByLabel(t *testing.T) {
+	dev, err := FindBlockDevice([]byte(lsblkUSBSample), "LABEL", "UNRAID")
+	if err != nil {
+		t.Fatalf("FindBlockDevice returned unexpected error: %v", err)
+	}
+
+	if dev.Name != "/dev/sda1" || dev.Mountpoint != "/boot" {
+		t.Errorf("unexpected device: %+v", dev)
+	}
+}
+
+func TestFindBlockDevice_NotFound(t *testing.T) {
+	for _, key := range []string{"LABEL", "UUID", "PARTUUID"} {
+		_, err := FindBlockDevice([]byte(lsblkUSBSample), key, "missing")
+		if err == nil {
+			t.Errorf("expected error for %s=missing, got none", key)
+		}
+	}
+
+	_, err := FindBlockDevice([]byte(`{not valid json`), "LABEL", "KEYS")
+	if err == nil {
+		t.Error("FindBlockDevice should return an error for malformed JSON")
+	}
+}
@@ -23,10 +23,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/constants"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/httpsig"
 	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/state"
 	"github.com/manifoldco/promptui"
 	"github.com/rclone/rclone/fs/config/obscure"
 	"github.com/rs/zerolog"
@@ -48,9 +57,54 @@ func (a *AutoUnlock) ObscureSecretFromStdin() error {
 		return fmt.Errorf("failed to obscure secret: %w", err)
 	}
 
-	fmt.Println(obscured)
+	return a.emit(map[string]string{"obscured": obscured}, func() error {
+		fmt.Println(obscured)
 
-	return nil
+		return nil
+	})
+}
+
+// readPassphrase reads a passphrase from the terminal without echo, asking twice if
+// confirm is set, or from the first line of stdin when it is not a terminal.
+func readPassphrase(confirm bool) ([]byte, error) {
+	stdin := int(os.Stdin.Fd()) // #nosec G115
+
+	if !term.IsTerminal(stdin) {
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("failed to read passphrase from stdin: %w", scanner.Err())
+		}
+
+		return scanner.Bytes(), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Recovery passphrase: ")
+
+	passphrase, err := term.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	if !confirm {
+		return passphrase, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+
+	confirmation, err := term.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+
+	if string(passphrase) != string(confirmation) {
+		return nil, errors.New("passphrases do not match")
+	}
+
+	return passphrase, nil
 }
 
 // FetchShareFromStdin reads a path from stdin, fetches the share at that path,
@@ -64,7 +118,19 @@ func (a *AutoUnlock) FetchShareFromStdin() error {
 
 	path := scanner.Text()
 
-	result, err := secrets.FetchShare(context.Background(), path)
+	// The parent sends SIGTERM when it no longer needs the share. Cancelling instead of
+	// dying lets the fetcher run its cleanup, such as unmounting a device.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	if a.args.FetchShare != nil && a.args.FetchShare.Deadline > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(a.args.FetchShare.Deadline))
+		defer cancel()
+	}
+
+	result, err := secrets.FetchShare(httpsig.WithKeyFile(ctx, a.args.DeviceKey), path)
 	if err != nil {
 		return fmt.Errorf("failed to fetch share: %w", err)
 	}
@@ -102,7 +168,7 @@ func (a *AutoUnlock) InitializeLogging() {
 // Prechecks verifies the system is ready for auto-unlock operations.
 func (a *AutoUnlock) Prechecks() error {
 	if !a.unraid.IsUnraid() {
-		return errors.New("not running on Unraid")
+		return withCode(CodeNotUnraid, errors.New("not running on Unraid"))
 	}
 
 	err := a.unraid.WaitForVarIni()
@@ -132,41 +198,98 @@ func (a *AutoUnlock) RemoveKeyfile() {
 	log.Info().Str("keyfile", a.args.KeyFile).Msg("Removed keyfile")
 }
 
-// TestPath tests access to a given path.
+// TestPath tests access to a given path. Each mirror of a fallback chain is tested
+// separately so that a broken mirror is not hidden by a working one.
 func (a *AutoUnlock) TestPath() error {
+	mirrors := secrets.SplitMirrors(a.args.TestPath.Path)
+	if len(mirrors) == 0 {
+		return errors.New("no path given")
+	}
+
+	var failed []error
+
+	results := make([]secrets.CheckResult, len(mirrors))
+
+	for mirror, path := range mirrors {
+		results[mirror].Mirror = mirror
+
+		err := a.testMirror(path, &results[mirror])
+		if err != nil {
+			log.Error().Int("mirror", mirror).Err(err).Msg("Mirror test failed")
+
+			results[mirror].Error = err.Error()
+			failed = append(failed, fmt.Errorf("mirror %d: %w", mirror, err))
+
+			continue
+		}
+
+		log.Info().Int("mirror", mirror).Msg("Successfully retrieved and verified share")
+	}
+
+	// Text output is the log above.
+	err := a.emit(results, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return errors.Join(failed...)
+	}
+
+	return nil
+}
+
+// testMirror fetches and verifies the share at path, recording the fetcher, latency
+// and share in result.
+func (a *AutoUnlock) testMirror(path string, result *secrets.CheckResult) error {
+	fetcher, err := secrets.MatchFetcher(path)
+	if err != nil {
+		return err //nolint:wrapcheck // Names the path
+	}
+
+	result.Fetcher = fetcher.Name()
+
 	ctx, cancel := context.WithTimeout(
 		context.Background(),
 		time.Duration(a.args.TestPath.ServerTimeout)*time.Second,
 	)
 	defer cancel()
 
-	shareStr, err := secrets.FetchShare(ctx, a.args.TestPath.Path)
+	start := time.Now()
+	shareStr, err := secrets.FetchShare(ctx, path)
+	result.LatencyMS = time.Since(start).Milliseconds()
+
 	if err != nil {
 		return fmt.Errorf("failed to fetch share: %w", err)
 	}
 
 	log.Info().Msg("Retrieved share from remote server")
 
-	appState, err := a.state.ReadStateFromFile(a.args.State)
+	appState, err := a.readState()
 	if err != nil {
-		return fmt.Errorf("failed to read state from file: %w", err)
+		return err
 	}
 
-	_, err = a.secrets.GetShare(shareStr, appState.SigningKey)
+	share, err := a.secrets.GetShare(shareStr, appState.SigningKey)
 	if err != nil {
 		return fmt.Errorf("failed to decode/verify share: %w", err)
 	}
 
-	log.Info().Msg("Successfully retrieved and verified share")
+	result.ShareID = strconv.FormatUint(uint64(share.Identifier()), 10)
 
 	return nil
 }
 
 // ResetConfiguration resets the auto-unlock configuration.
 func (a *AutoUnlock) ResetConfiguration() error {
+	// The prompt would mix with the result document on stdout.
+	if !a.args.Reset.Force && a.args.Output == OutputJSON {
+		return errors.New("--force is required with --output json")
+	}
+
 	if !a.args.Reset.Force {
 		prompt := promptui.Prompt{
-			Label:     "Are you sure you want to reset the auto-unlock configuration? This will delete the state and encrypted files",
+			Label:     "Are you sure you want to reset the auto-unlock configuration? This will delete the state, encrypted and recovery files",
 			IsConfirm: true,
 			Default:   "N",
 		}
@@ -179,7 +302,13 @@ func (a *AutoUnlock) ResetConfiguration() error {
 		}
 	}
 
-	files := []string{a.args.State, a.args.EncryptedFile, a.args.Config}
+	files := []string{
+		a.args.State,
+		a.args.EncryptedFile,
+		a.args.Config,
+		a.args.Config + legacyConfigSuffix,
+		a.args.RecoveryFile,
+	}
 	for _, file := range files {
 		err := a.safeRemoveFile(file)
 		if err != nil {
@@ -190,6 +319,154 @@ func (a *AutoUnlock) ResetConfiguration() error {
 	return nil
 }
 
+// readState reads the state file, telling a missing one, as before setup, from one
+// that cannot be read.
+func (a *AutoUnlock) readState() (state.State, error) {
+	appState, err := a.state.ReadStateFromFile(a.args.State)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state.State{}, withCode(
+			CodeStateMissing,
+			fmt.Errorf("failed to read state from file: %w", err),
+		)
+	}
+
+	if err != nil {
+		return state.State{}, withCode(
+			CodeStateInvalid,
+			fmt.Errorf("failed to read state from file: %w", err),
+		)
+	}
+
+	return appState, nil
+}
+
+// legacyConfigSuffix is appended to the config file name to keep the original of a
+// migrated legacy config.
+const legacyConfigSuffix = ".legacy"
+
+// loadConfig reads the configured share locations without changing the file, so that
+// unlocking never rewrites the config on the flash drive.
+func (a *AutoUnlock) loadConfig() (config.Config, error) {
+	cfg, _, err := a.secrets.ReadConfig(a.args.Config)
+	if err != nil {
+		return config.Config{}, withCode(
+			CodeConfigInvalid,
+			fmt.Errorf("failed to read config file: %w", err),
+		)
+	}
+
+	return cfg, nil
+}
+
+// migrateLegacyConfig converts a config file in the legacy line format to YAML in place,
+// keeping the original next to it with a .legacy suffix. It is only run by setup and
+// validate. A missing or unreadable file is left for the commands that use it to report.
+func (a *AutoUnlock) migrateLegacyConfig() {
+	cfg, format, err := a.secrets.ReadConfig(a.args.Config)
+	if err != nil || format != config.FormatLegacy {
+		return
+	}
+
+	err = a.migrateConfig(cfg)
+	if err != nil {
+		log.Warn().Err(err).Str("config", a.args.Config).Msg("Failed to migrate legacy config")
+	}
+}
+
+func (a *AutoUnlock) migrateConfig(cfg config.Config) error {
+	original, err := afero.ReadFile(a.fs, a.args.Config)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	dropped := nameLegacyLocations(&cfg, original)
+
+	encoded, err := config.EncodeYAML(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	backup := a.args.Config + legacyConfigSuffix
+
+	err = afero.WriteFile(a.fs, backup, original, constants.StateFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to back up config file: %w", err)
+	}
+
+	header := fmt.Sprintf("# Migrated from the line-based format. The original is kept in %s\n", backup)
+
+	err = afero.WriteFile(a.fs, a.args.Config, append([]byte(header), encoded...), constants.StateFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	log.Info().
+		Str("config", a.args.Config).
+		Str("backup", backup).
+		Msg("Migrated legacy config to YAML")
+
+	if dropped > 0 {
+		log.Warn().
+			Int("comments", dropped).
+			Str("backup", backup).
+			Msg("Comments that do not label a location were not migrated, see the backup")
+	}
+
+	return nil
+}
+
+// nameLegacyLocations names each location after the comment on the line just before it,
+// as legacy files often label their locations that way. It returns the number of
+// comments that could not be carried over.
+func nameLegacyLocations(cfg *config.Config, original []byte) int {
+	var (
+		comment string
+		dropped int
+		index   int
+		names   = make(map[string]bool)
+	)
+
+	for line := range strings.Lines(string(original)) {
+		line = strings.TrimSpace(line)
+
+		if text, found := strings.CutPrefix(line, "#"); found {
+			if comment != "" {
+				dropped++
+			}
+
+			comment = strings.TrimSpace(text)
+
+			continue
+		}
+
+		if len(secrets.SplitMirrors(line)) == 0 {
+			// A blank line separates the comment from the next location.
+			if comment != "" {
+				dropped++
+				comment = ""
+			}
+
+			continue
+		}
+
+		if comment != "" && index < len(cfg.Locations) && !names[comment] {
+			cfg.Locations[index].Name = comment
+			names[comment] = true
+		} else if comment != "" {
+			dropped++
+		}
+
+		comment = ""
+		index++
+	}
+
+	if comment != "" {
+		dropped++
+	}
+
+	return dropped
+}
+
 func (a *AutoUnlock) safeRemoveFile(file string) error {
 	err := a.fs.Remove(file)
 	if errors.Is(err, afero.ErrFileNotFound) {
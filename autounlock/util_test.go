@@ -19,8 +19,11 @@ package main
 */
 
 import (
+	"slices"
 	"testing"
 
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
 	"github.com/spf13/afero"
 )
 
@@ -31,6 +34,9 @@ import (
 // - Test safeRemoveFile with various scenarios
 // - Test that InitializeLogging doesn't panic
 // - Test Prechecks with Unraid and non-Unraid environments
+// - Test migrateLegacyConfig migrates legacy config files to YAML and keeps a backup
+// - Ensure that loadConfig, as used by unlock, never rewrites the config file
+// - Verify that the comment before a legacy location becomes its name when migrated
 
 func TestRemoveKeyfile_FileExists(t *testing.T) {
 	fs := afero.NewMemMapFs()
@@ -259,3 +265,77 @@ func TestInitializeLogging_WithPrettyFlag(t *testing.T) {
 
 	autoUnlock.InitializeLogging()
 }
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	configPath := "/boot/config/plugins/auto-unlock/config.txt"
+	legacy := "# primary\nhttps://nas/share || dns:share.example.com\n"
+
+	err := afero.WriteFile(fs, configPath, []byte(legacy), 0o600)
+	if err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	autoUnlock := &AutoUnlock{
+		fs:      fs,
+		args:    CmdArgs{Config: configPath},
+		secrets: secrets.NewService(fs, "", false, false),
+	}
+
+	cfg, err := autoUnlock.loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+
+	// Loading for unlock leaves the file alone.
+	unchanged, err := afero.ReadFile(fs, configPath)
+	if err != nil || string(unchanged) != legacy {
+		t.Errorf("config = %q, err = %v, want it unchanged", unchanged, err)
+	}
+
+	exists, _ := afero.Exists(fs, configPath+".legacy")
+	if exists {
+		t.Error("loadConfig created a backup")
+	}
+
+	autoUnlock.migrateLegacyConfig()
+
+	backup, err := afero.ReadFile(fs, configPath+".legacy")
+	if err != nil || string(backup) != legacy {
+		t.Errorf("backup = %q, err = %v, want original file", backup, err)
+	}
+
+	// Loading again reads the migrated file, which holds the same locations.
+	migrated, format, err := autoUnlock.secrets.ReadConfig(configPath)
+	if err != nil {
+		t.Fatalf("ReadConfig failed: %v", err)
+	}
+
+	if format != config.FormatYAML || len(migrated.Locations) != 1 ||
+		!slices.Equal(migrated.Locations[0].Mirrors(), cfg.Locations[0].Mirrors()) ||
+		migrated.Locations[0].Name != "primary" {
+		t.Errorf("unexpected migrated config: %s %+v", format, migrated)
+	}
+}
+
+// TestNameLegacyLocations tests carrying legacy comments over as location names.
+func TestNameLegacyLocations(t *testing.T) {
+	legacy := "# Share locations\n\n# nas\nhttps://nas/share\ndns:share.example.com\n" +
+		"# nas\nhttps://backup/share\n#\n# trailing\n"
+
+	cfg := config.Config{Locations: secrets.LocationsFromPaths(
+		[]string{"https://nas/share", "dns:share.example.com", "https://backup/share"},
+	)}
+
+	dropped := nameLegacyLocations(&cfg, []byte(legacy))
+
+	names := []string{cfg.Locations[0].Name, cfg.Locations[1].Name, cfg.Locations[2].Name}
+	if !slices.Equal(names, []string{"nas", "", ""}) {
+		t.Errorf("names = %q, want [nas  ]", names)
+	}
+
+	// The header, the repeated name and the trailing comment are not carried over.
+	if dropped != 3 {
+		t.Errorf("dropped = %d, want 3", dropped)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+/*
+	autounlock - Unraid Auto Unlock
+	Copyright (C) 2025-2026 Derek Kaser
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dkaser/unraid-auto-unlock/autounlock/config"
+	"github.com/dkaser/unraid-auto-unlock/autounlock/secrets"
+	"github.com/rs/zerolog/log"
+)
+
+// ValidateResult is the result of validate.
+type ValidateResult struct {
+	secrets.ValidationReport
+
+	Format config.Format `json:"format"`
+	// Threshold is 0 when the state could not be read.
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// ValidateConfig checks the configuration file without contacting any location and
+// prints the findings. Locations are identified by number and name only, since their
+// targets may contain credentials. The file is never changed; a config in the legacy line
+// format is checked as it is and reported with a warning, since setup migrates it.
+func (a *AutoUnlock) ValidateConfig() error {
+	cfg, format, err := a.secrets.ReadConfig(a.args.Config)
+	if err != nil {
+		return withCode(CodeConfigInvalid, fmt.Errorf("failed to read config file: %w", err))
+	}
+
+	threshold := 0
+
+	appState, err := a.state.ReadStateFromFile(a.args.State)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read state, not checking the threshold")
+	} else {
+		threshold = int(appState.Threshold)
+	}
+
+	result := ValidateResult{
+		ValidationReport: secrets.ValidateConfig(cfg, threshold),
+		Format:           format,
+		Threshold:        threshold,
+	}
+
+	if format == config.FormatLegacy {
+		result.Findings = append(result.Findings, secrets.Finding{
+			Severity: secrets.SeverityWarning,
+			Message:  "config uses the legacy line format; run setup to migrate it to YAML",
+		})
+	}
+
+	err = a.emit(result, func() error { return printValidateResult(result) })
+	if err != nil {
+		return err
+	}
+
+	if result.HasErrors() {
+		return withCode(CodeConfigInvalid, errors.New("configuration has errors"))
+	}
+
+	return nil
+}
+
+func printValidateResult(result ValidateResult) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd // Column padding
+	fmt.Fprintln(writer, "LOCATION\tMIRROR\tNAME\tFETCHER\tRESULT")
+
+	for _, target := range result.Targets {
+		results := []string{"ok"}
+		if len(target.Findings) > 0 {
+			results = results[:0]
+			for _, finding := range target.Findings {
+				results = append(results, string(finding.Severity)+": "+finding.Message)
+			}
+		}
+
+		for _, result := range results {
+			fmt.Fprintf(
+				writer,
+				"%d\t%d\t%s\t%s\t%s\n",
+				target.Location,
+				target.Mirror,
+				target.Name,
+				target.Fetcher,
+				result,
+			)
+		}
+	}
+
+	err := writer.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	fmt.Printf("\nFormat: %s, usable locations: %d", result.Format, result.Usable)
+
+	if result.Threshold > 0 {
+		fmt.Printf(", threshold: %d", result.Threshold)
+	}
+
+	fmt.Println()
+
+	for _, finding := range result.Findings {
+		fmt.Printf("%s: %s\n", finding.Severity, finding.Message)
+	}
+
+	return nil
+}
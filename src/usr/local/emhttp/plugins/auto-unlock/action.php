@@ -56,6 +56,10 @@ $app->post("{$prefix}/test", function (Request $request, Response $response, $ar
     return Actions::Test($request, $response);
 });
 
+$app->post("{$prefix}/check", function (Request $request, Response $response, $args) {
+    return Actions::Check($request, $response);
+});
+
 $app->post("{$prefix}/test_path", function (Request $request, Response $response, $args) {
     return Actions::TestPath($request, $response);
 });
@@ -138,6 +138,7 @@ if ( ! file_exists(Utils::STATE_FILE) && ! file_exists(Utils::ENC_FILE)) {
         const sharesTotal   = document.getElementById('shares_total').value;
         const sharesUnlock  = document.getElementById('shares_unlock').value;
         const passphrase    = document.getElementById('passphrase').value;
+        const recovery      = document.getElementById('recovery_passphrase').value;
         const keyfileInput  = document.getElementById('keyfile');
         let keyfileContent  = null;
 
@@ -148,20 +149,21 @@ if ( ! file_exists(Utils::STATE_FILE) && ! file_exists(Utils::ENC_FILE)) {
             const fileReader = new FileReader();
             fileReader.onload = function(event) {
                 keyfileContent = event.target.result;
-                submitInitialization(sharesTotal, sharesUnlock, keyfileContent);
+                submitInitialization(sharesTotal, sharesUnlock, keyfileContent, recovery);
             };
             fileReader.readAsDataURL(keyfileInput.files[0]);
         } else {
             keyfileContent = window.btoa(unescape(encodeURIComponent(passphrase)));
-            submitInitialization(sharesTotal, sharesUnlock, keyfileContent);
+            submitInitialization(sharesTotal, sharesUnlock, keyfileContent, recovery);
         }
     }
 
-    async function submitInitialization(sharesTotal, sharesUnlock, keyfileContent) {
+    async function submitInitialization(sharesTotal, sharesUnlock, keyfileContent, recovery) {
         const formData = new URLSearchParams({
             'shares_total': sharesTotal,
             'shares_unlock': sharesUnlock,
             'keyfile_data': keyfileContent,
+            'recovery_passphrase': recovery,
             'csrf_token': <?= json_encode($csrfToken); ?>
         });
 
@@ -184,6 +186,7 @@ if ( ! file_exists(Utils::STATE_FILE) && ! file_exists(Utils::ENC_FILE)) {
         const sharesTotal   = document.getElementById('shares_total').value;
         const sharesUnlock  = document.getElementById('shares_unlock').value;
         const passphrase    = document.getElementById('passphrase').value;
+        const recovery      = document.getElementById('recovery_passphrase').value;
         const keyfileInput  = document.getElementById('keyfile');
 
         let isValid = true;
@@ -200,6 +203,10 @@ if ( ! file_exists(Utils::STATE_FILE) && ! file_exists(Utils::ENC_FILE)) {
             isValid = false;
         }
 
+        if (recovery.length > 0 && recovery.length < 12) {
+            isValid = false;
+        }
+
         document.getElementById('initialize').disabled = !isValid;
     }
 </script>
@@ -232,6 +239,13 @@ if ( ! file_exists(Utils::STATE_FILE) && ! file_exists(Utils::ENC_FILE)) {
             <input type="button" id="clear_file" name="clear_file" value="<?= $tr->tr("clear"); ?>" disabled onclick="clearKeyfile()" />
         </dd>
     </dl>
+    <dl>
+        <dt><?= $tr->tr("recovery_passphrase"); ?></dt>
+        <dd>
+            <input type="password" id="recovery_passphrase" name="recovery_passphrase" value="" minlength="12" oninput="verifyInitializeInputs()" />
+            <br><?= $tr->tr("recovery_passphrase_instructions"); ?>
+        </dd>
+    </dl>
     <dl>
         <dt><?= $tr->tr("initialize"); ?></dt>
         <dd>
@@ -311,8 +325,70 @@ if ( ! file_exists(Utils::STATE_FILE) && ! file_exists(Utils::ENC_FILE)) {
         document.getElementById('continue_button').disabled = false;
     }
 
+    async function checkLocations() {
+        const formData = new URLSearchParams({
+            'csrf_token': <?= json_encode($csrfToken); ?>
+        });
+        const button = document.getElementById('check_locations_button');
+        const table = document.getElementById('check_results');
+        const rows = table.querySelector('tbody');
+        const summary = document.getElementById('check_summary');
+
+        button.disabled = true;
+        rows.replaceChildren();
+        summary.textContent = '';
+
+        try {
+            const response = await fetch('/plugins/auto-unlock/action.php/check', {
+                method: 'POST',
+                body: formData,
+                signal: AbortSignal.timeout(150000)
+            });
+            const report = await response.json();
+            if (report.error) {
+                throw new Error(report.error);
+            }
+
+            for (const result of report.results) {
+                let status = 'OK';
+                if (result.error) {
+                    status = result.error;
+                } else if (result.skipped) {
+                    status = <?= json_encode($tr->tr("skipped")); ?>;
+                } else if (result.duplicateOf !== undefined) {
+                    status = <?= json_encode($tr->tr("duplicate_of")); ?> + ' ' + result.duplicateOf;
+                }
+
+                const row = rows.insertRow();
+                for (const value of [
+                    result.mirror ? result.location + '.' + result.mirror : result.location,
+                    result.name || '',
+                    result.fetcher || '',
+                    result.latencyMs + ' ms',
+                    result.shareId || '',
+                    status
+                ]) {
+                    row.insertCell().textContent = value;
+                }
+            }
+
+            const message = report.reachable
+                ? <?= json_encode($tr->tr("threshold_reachable")); ?>
+                : <?= json_encode($tr->tr("threshold_unreachable")); ?>;
+            summary.textContent = message
+                .replace('{shares}', report.shares)
+                .replace('{threshold}', report.threshold);
+            table.style.display = '';
+        } catch (error) {
+            summary.textContent = 'Error during location check: ' + error.message;
+        }
+        button.disabled = false;
+    }
+
     async function unlockArray() {
+        const recoveryInput = document.getElementById('recovery_passphrase');
         const formData = new URLSearchParams({
+            'recovery_passphrase': recoveryInput ? recoveryInput.value : '',
             'csrf_token': <?= json_encode($csrfToken); ?>
         });
 
@@ -362,6 +438,15 @@ if ( ! file_exists(Utils::STATE_FILE) && ! file_exists(Utils::ENC_FILE)) {
         <input type="button" id="unlock_array_button" name="unlock_array_button" value="<?= $tr->tr("unlock_array"); ?>" onclick="unlockArray()" <?= $arrayStopped ? '' : 'disabled'; ?> />
     </dd>
 </dl>
+<?php if (file_exists(Utils::RECOVERY_FILE)) { ?>
+<dl>
+    <dt><?= $tr->tr("recovery_passphrase"); ?></dt>
+    <dd>
+        <input type="password" id="recovery_passphrase" name="recovery_passphrase" value="" />
+        <br><?= $tr->tr("recovery_unlock_instructions"); ?>
+    </dd>
+</dl>
+<?php } ?>
 
 <table class="unraid tablesorter"><thead><tr><td><?= $tr->tr("test_path"); ?></td></tr></thead></table>
 <dl>
@@ -380,6 +465,27 @@ if ( ! file_exists(Utils::STATE_FILE) && ! file_exists(Utils::ENC_FILE)) {
     </dd>
 </dl>
 
+<table class="unraid tablesorter"><thead><tr><td><?= $tr->tr("check_locations"); ?></td></tr></thead></table>
+<p><?= $tr->tr("check_locations_instructions"); ?></p>
+<dl>
+    <dt><?= $tr->tr("check_locations"); ?></dt>
+    <dd>
+        <input type="button" id="check_locations_button" name="check_locations_button" value="<?= $tr->tr("test"); ?>" onclick="checkLocations()" />
+    </dd>
+</dl>
+<table class="unraid" id="check_results" style="display:none;">
+    <thead><tr>
+        <td><?= $tr->tr("location"); ?></td>
+        <td><?= $tr->tr("name"); ?></td>
+        <td><?= $tr->tr("fetcher"); ?></td>
+        <td><?= $tr->tr("latency"); ?></td>
+        <td><?= $tr->tr("share"); ?></td>
+        <td><?= $tr->tr("result"); ?></td>
+    </tr></thead>
+    <tbody></tbody>
+</table>
+<p id="check_summary"></p>
+
 <table class="unraid tablesorter"><thead><tr><td><?= $tr->tr("obscure_value"); ?></td></tr></thead></table>
 <dl>
     <dt><?= $tr->tr("obscure_value"); ?></dt>
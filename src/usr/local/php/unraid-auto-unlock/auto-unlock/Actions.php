@@ -31,8 +31,9 @@ class Actions
      * Stream a Symfony Process output directly via echo, bypassing Slim's buffering.
      * Uses getIncrementalOutput and disables output buffering for true streaming.
      * MUST call sendStreamHeaders() before calling this function.
+     * Stdout is not echoed for commands run with --output json; use printResult instead.
      */
-    private static function streamProcess(Process $process, ?string $startMsg = null, ?string $timeoutMsg = null): int
+    private static function streamProcess(Process $process, ?string $startMsg = null, ?string $timeoutMsg = null, bool $echoOutput = true): int
     {
         if ($startMsg) {
             echo $startMsg;
@@ -62,7 +63,7 @@ class Actions
             echo $err;
             flush();
         }
-        if ($out) {
+        if ($out && $echoOutput) {
             echo $out;
             flush();
         }
@@ -70,6 +71,38 @@ class Actions
         return $process->getExitCode() ?? -1;
     }
 
+    /**
+     * Print the result line from the JSON result document of a command run with --output json.
+     * Returns the data of a successful command, or null.
+     * Falls back to the exit code if the command did not print a result document.
+     */
+    private static function printResult(Process $process, int $exitCode): mixed
+    {
+        if ($exitCode === -1) {
+            // The timeout was already reported by streamProcess
+            return null;
+        }
+
+        $result = json_decode($process->getOutput(), true);
+        if ( ! is_array($result) || ! isset($result['status'])) {
+            echo $exitCode === 0 ? "Result: SUCCESS\n" : "Result: FAIL\n";
+            flush();
+            return null;
+        }
+
+        if ($result['status'] !== 'ok') {
+            $code  = (string) ($result['code'] ?? 'failed');
+            $error = (string) ($result['error'] ?? '');
+            echo "Result: FAIL ({$code}): {$error}\n";
+            flush();
+            return null;
+        }
+
+        echo "Result: SUCCESS\n";
+        flush();
+        return $result['data'] ?? null;
+    }
+
     /**
      * Send headers and disable output buffering for streaming responses.
      * Call this before using streamProcess and then exit after streaming.
@@ -92,6 +125,9 @@ class Actions
 
     public static function Unlock(Request $request, Response $response): Response
     {
+        $data       = (array) $request->getParsedBody();
+        $passphrase = isset($data['recovery_passphrase']) ? (string) $data['recovery_passphrase'] : '';
+
         self::sendStreamHeaders();
 
         echo "Checking for existing unlock processes\n";
@@ -119,23 +155,29 @@ class Actions
             exit(1);
         }
 
-        $process = new Process([
+        $command = [
             self::BIN_PATH,
+            '--output',
+            'json',
             'unlock',
             '--pretty'
-        ]);
+        ];
+        if ($passphrase !== '') {
+            $command[] = '--passphrase';
+        }
+
+        $process = new Process($command);
+        if ($passphrase !== '') {
+            $process->setInput($passphrase . "\n");
+        }
         $process->setTimeout(300);
         $exitCode = self::streamProcess(
             $process,
             "Unlocking Drives\n",
-            "Result: TIMEOUT\n"
+            "Result: TIMEOUT\n",
+            false
         );
-        if ($exitCode === 0) {
-            echo "Result: SUCCESS\n";
-        } else {
-            echo "Result: FAIL\n";
-        }
-        flush();
+        self::printResult($process, $exitCode);
         exit(0);
     }
 
@@ -145,6 +187,8 @@ class Actions
 
         $process = new Process([
             self::BIN_PATH,
+            '--output',
+            'json',
             'unlock',
             '--pretty',
             '--debug',
@@ -154,17 +198,47 @@ class Actions
         $exitCode = self::streamProcess(
             $process,
             "Testing Configuration\n",
-            "Result: TIMEOUT\n"
+            "Result: TIMEOUT\n",
+            false
         );
-        if ($exitCode === 0) {
-            echo "Result: SUCCESS\n";
-        } else {
-            echo "Result: FAIL\n";
+        $data = self::printResult($process, $exitCode);
+        if (is_array($data) && isset($data['shares'], $data['threshold'])) {
+            echo "Shares combined: {$data['shares']} of {$data['threshold']} required\n";
+            flush();
         }
-        flush();
         exit(0);
     }
 
+    public static function Check(Request $request, Response $response): Response
+    {
+        $process = new Process([
+            self::BIN_PATH,
+            '--output',
+            'json',
+            'check'
+        ]);
+        $process->setTimeout(120);
+
+        try {
+            $process->run();
+        } catch (ProcessTimedOutException $e) {
+            $response->getBody()->write(json_encode(['error' => 'Check timed out.']) ?: '');
+            return $response->withHeader('Content-Type', 'application/json')->withStatus(504);
+        }
+
+        // The report is included even when the threshold is not reachable
+        $result = json_decode($process->getOutput(), true);
+        if ( ! is_array($result) || ! isset($result['data'])) {
+            $error = is_array($result) && isset($result['error']) ? (string) $result['error'] : '';
+            $error = $error ?: (trim($process->getErrorOutput()) ?: 'Check failed.');
+            $response->getBody()->write(json_encode(['error' => $error]) ?: '');
+            return $response->withHeader('Content-Type', 'application/json')->withStatus(500);
+        }
+
+        $response->getBody()->write(json_encode($result['data']) ?: '');
+        return $response->withHeader('Content-Type', 'application/json')->withStatus(200);
+    }
+
     public static function Remove(Request $request, Response $response): Response
     {
         Utils::removeConfigFiles();
@@ -209,6 +283,7 @@ class Actions
         $sharesTotal  = isset($data['shares_total']) ? (int) $data['shares_total'] : 5;
         $sharesUnlock = isset($data['shares_unlock']) ? (int) $data['shares_unlock'] : 3;
         $keyfileData  = isset($data['keyfile_data']) ? (string) $data['keyfile_data'] : null;
+        $passphrase   = isset($data['recovery_passphrase']) ? (string) $data['recovery_passphrase'] : '';
 
         $keyFileParts   = explode(';base64,', $keyfileData ?? '');
         $keyFileContent = end($keyFileParts) ?: '';
@@ -223,6 +298,11 @@ class Actions
             return $response->withStatus(400);
         }
 
+        if ($passphrase !== '' && mb_strlen($passphrase) < 12) {
+            $body->write("Error: Recovery passphrase must be at least 12 characters.");
+            return $response->withStatus(400);
+        }
+
         $decodedKeyfile = base64_decode($keyFileContent, true);
         if ($decodedKeyfile === false) {
             $body->write("Error: Invalid keyfile encoding.");
@@ -240,23 +320,33 @@ class Actions
         }
         $process = null;
         try {
-            $process = new Process([
+            $command = [
                 self::BIN_PATH,
+                '--output',
+                'json',
                 'setup',
                 '--pretty',
                 '--shares', $sharesTotal,
                 '--threshold', $sharesUnlock
-            ]);
+            ];
+            if ($passphrase !== '') {
+                $command[] = '--recovery';
+            }
+
+            $process = new Process($command);
+            if ($passphrase !== '') {
+                $process->setInput($passphrase . "\n");
+            }
             $process->setTimeout(120);
             $exitCode = self::streamProcess(
                 $process,
                 "Initializing...\n",
-                "Result: TIMEOUT\n"
+                "Result: TIMEOUT\n",
+                false
             );
-            if ($exitCode === 0) {
-                echo "Result: SUCCESS\n";
-            } else {
-                echo "Result: FAIL\n";
+            $data = self::printResult($process, $exitCode);
+            if (is_array($data)) {
+                self::printSetupResult($data);
             }
         } finally {
             // Clean up temporary keyfile if it still exists
@@ -277,6 +367,8 @@ class Actions
 
         $process = new Process([
             self::BIN_PATH,
+            '--output',
+            'json',
             'testpath',
             '--pretty',
             '--debug',
@@ -284,14 +376,34 @@ class Actions
         ]);
         $exitCode = self::streamProcess(
             $process,
-            "Testing path: {$testPath}\n"
+            "Testing path: {$testPath}\n",
+            null,
+            false
         );
-        if ($exitCode === 0) {
-            echo "Result: SUCCESS\n";
-        } else {
-            echo "Result: FAIL\n";
+        self::printResult($process, $exitCode);
+        exit(0);
+    }
+
+    /**
+     * Print the shares and device key from the result of setup.
+     *
+     * @param array<mixed> $data
+     */
+    private static function printSetupResult(array $data): void
+    {
+        $shares = is_array($data['shares'] ?? null) ? $data['shares'] : [];
+
+        echo "\nTotal Shares: " . count($shares) . "\n";
+        echo "Unlock Threshold: " . (int) ($data['threshold'] ?? 0) . "\n\n";
+        echo "Share values (base64 encoded):\n";
+        foreach ($shares as $share) {
+            echo "{$share}\n";
+        }
+
+        echo "\nDevice public key: " . (string) ($data['devicePublicKey'] ?? '') . "\n";
+        if ( ! empty($data['recoveryFile'])) {
+            echo "Recovery share: {$data['recoveryFile']}\n";
         }
         flush();
-        exit(0);
     }
 }
@@ -21,9 +21,10 @@ namespace AutoUnlock;
 
 class Utils extends \EDACerton\PluginUtils\Utils
 {
-    public const STATE_FILE  = "/boot/config/plugins/auto-unlock/state.json";
-    public const ENC_FILE    = "/boot/config/plugins/auto-unlock/unlock.enc";
-    public const CONFIG_FILE = "/boot/config/plugins/auto-unlock/config.txt";
+    public const STATE_FILE    = "/boot/config/plugins/auto-unlock/state.json";
+    public const ENC_FILE      = "/boot/config/plugins/auto-unlock/unlock.enc";
+    public const CONFIG_FILE   = "/boot/config/plugins/auto-unlock/config.txt";
+    public const RECOVERY_FILE = "/boot/config/plugins/auto-unlock/recovery.json";
 
     public static function removeConfigFiles(): void
     {
@@ -33,6 +34,12 @@ class Utils extends \EDACerton\PluginUtils\Utils
         if (file_exists(self::ENC_FILE)) {
             unlink(self::ENC_FILE);
         }
+        if (file_exists(self::RECOVERY_FILE)) {
+            unlink(self::RECOVERY_FILE);
+        }
+        if (file_exists(self::CONFIG_FILE . ".legacy")) {
+            unlink(self::CONFIG_FILE . ".legacy");
+        }
     }
 
     public static function getCsrfToken(): string